@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/Aiyanu/gophersocial/internal/mailer"
+)
+
+type DeleteAccountPayload struct {
+	Password string `json:"password" validate:"required,max=72"`
+}
+
+type AccountDeletion struct {
+	DeleteAfter time.Time `json:"delete_after"`
+}
+
+// DeleteAccount godoc
+//
+//	@Summary		Deletes the user's account
+//	@Description	Deactivates the authenticated user's account right away and deletes it for good once the grace period ends. Logging in before then restores it.
+//	@Tags			users
+//	@Accept			json
+//	@Produce		json
+//	@Param			payload	body		DeleteAccountPayload	true	"Password confirmation"
+//	@Success		202		{object}	AccountDeletion
+//	@Failure		400		{object}	error
+//	@Failure		403		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/users/me [delete]
+func (app *application) deleteAccountHandler(w http.ResponseWriter, r *http.Request) {
+	user := getUserFromContext(r)
+
+	var payload DeleteAccountPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	ctx := r.Context()
+
+	// The cached user carries no password hash.
+	account, err := app.store.Users.GetByEmail(ctx, user.Email)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+	if err := account.Password.Compare(payload.Password); err != nil {
+		app.forbiddenError(w, r, errInvalidPassword)
+		return
+	}
+
+	requestedAt, err := app.store.Users.RequestDeletion(ctx, user.ID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.cacheStorage.Users.Delete(ctx, user.ID); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	deletion := AccountDeletion{DeleteAfter: requestedAt.Add(app.config.deletionGrace)}
+
+	vars := struct {
+		Username    string
+		DeleteAfter string
+	}{
+		Username:    user.Username,
+		DeleteAfter: deletion.DeleteAfter.Format("January 2, 2006"),
+	}
+
+	// The account is already deactivated, so a failed email is not worth
+	// undoing the request for.
+	isProdEnv := app.config.env == "production"
+	if _, err := app.mailer.Send(mailer.AccountDeletionTemplate, user.Username, user.Email, vars, !isProdEnv); err != nil {
+		app.logger.Errorw("error sending account deletion email", "user_id", user.ID, "error", err)
+	}
+
+	if err := app.jsonResponse(w, http.StatusAccepted, deletion); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// purgeDeletedAccounts hard-deletes the accounts whose grace period is
+// over.
+func (app *application) purgeDeletedAccounts(ctx context.Context) error {
+	return app.store.Users.PurgeDeleted(ctx, app.config.deletionGrace)
+}
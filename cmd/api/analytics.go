@@ -0,0 +1,67 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const maxAnalyticsDays = 90
+
+// flushPostStats writes the buffered view and impression counts to the
+// database. Counts that fail to flush are kept for the next run.
+func (app *application) flushPostStats(ctx context.Context) error {
+	deltas := app.postStats.Drain()
+	if len(deltas) == 0 {
+		return nil
+	}
+
+	if err := app.store.Stats.AddPostStats(ctx, deltas); err != nil {
+		app.postStats.Restore(deltas)
+		return err
+	}
+	return nil
+}
+
+// GetAnalytics godoc
+//
+//	@Summary		Fetches post analytics
+//	@Description	Fetches daily views and feed impressions of the authenticated user's posts with totals per post
+//	@Tags			users
+//	@Produce		json
+//	@Param			days	query		int	false	"Number of days to cover, up to 90"
+//	@Success		200		{object}	[]store.PostAnalytics
+//	@Failure		400		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/users/me/analytics [get]
+func (app *application) getAnalyticsHandler(w http.ResponseWriter, r *http.Request) {
+	days := 30
+	if d := r.URL.Query().Get("days"); d != "" {
+		var err error
+		days, err = strconv.Atoi(d)
+		if err != nil {
+			app.badRequestError(w, r, err)
+			return
+		}
+	}
+	if days < 1 || days > maxAnalyticsDays {
+		app.badRequestError(w, r, fmt.Errorf("days must be between 1 and %d", maxAnalyticsDays))
+		return
+	}
+
+	user := getUserFromContext(r)
+	since := time.Now().UTC().Truncate(24*time.Hour).AddDate(0, 0, 1-days)
+
+	stats, err := app.store.Stats.GetAuthorAnalytics(r.Context(), user.ID, since)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, stats); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
@@ -4,13 +4,16 @@ import (
 	"fmt"
 	"log"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/Aiyanu/gophersocial/docs"
+	"github.com/Aiyanu/gophersocial/internal/analytics"
 	"github.com/Aiyanu/gophersocial/internal/auth"
 	"github.com/Aiyanu/gophersocial/internal/mailer"
 	"github.com/Aiyanu/gophersocial/internal/store"
 	"github.com/Aiyanu/gophersocial/internal/store/cache"
+	"github.com/Aiyanu/gophersocial/internal/unfurl"
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 	httpSwagger "github.com/swaggo/http-swagger/v2"
@@ -24,6 +27,8 @@ type application struct {
 	logger        *zap.SugaredLogger
 	mailer        mailer.Client
 	authenticator auth.Authenticator
+	postStats     *analytics.Counter
+	unfurler      *unfurl.Client
 }
 
 type config struct {
@@ -35,6 +40,31 @@ type config struct {
 	frontendURL string
 	auth        authConfig
 	redisCfg    redisConfig
+	jobs        jobsConfig
+	unfurl      unfurlConfig
+
+	maxPinnedPosts   int
+	viewDedupWindow  time.Duration
+	usernameCooldown time.Duration
+	usernameGrace    time.Duration
+	deletionGrace    time.Duration
+	exportTTL        time.Duration
+}
+
+type jobsConfig struct {
+	trendingInterval   time.Duration
+	statsFlushInterval time.Duration
+	unfurlInterval     time.Duration
+	expiryInterval     time.Duration
+	purgeInterval      time.Duration
+	exportInterval     time.Duration
+	suggestionInterval time.Duration
+}
+
+type unfurlConfig struct {
+	timeout  time.Duration
+	maxBytes int64
+	ttl      time.Duration
 }
 
 type redisConfig struct {
@@ -96,28 +126,98 @@ func (app *application) mount() http.Handler {
 			r.Use(app.AuthTokenMiddleware)
 			r.Post("/", app.createPostHandler)
 			r.Get("/", app.createPostHandler)
+			r.Post("/preview", app.previewPostHandler)
 			r.Route("/{postID}", func(r chi.Router) {
 				r.Use(app.postsContextMiddleware)
 				r.Get("/", app.getPostHandler)
+				r.Post("/comments", app.createCommentHandler)
+				r.Get("/poll", app.getPollResultsHandler)
+				r.Post("/poll/votes", app.votePollHandler)
+				r.Put("/pin", app.pinPostHandler)
+				r.Delete("/pin", app.unpinPostHandler)
+				r.Put("/series", app.addPostToSeriesHandler)
+				r.Delete("/series", app.removePostFromSeriesHandler)
+				r.Put("/expiry", app.setPostExpiryHandler)
+				r.Put("/sensitive", app.checkPostOwnership("moderator", app.markPostSensitiveHandler))
 				r.Patch("/", app.checkPostOwnership("moderator", app.updatePostHandler))
 				r.Delete("/", app.checkPostOwnership("admin", app.deletePostHandler))
 			})
 		})
+		r.Route("/threads", func(r chi.Router) {
+			r.Use(app.AuthTokenMiddleware)
+			r.Post("/", app.createThreadHandler)
+			r.Get("/{threadID}", app.getThreadHandler)
+		})
+		r.Route("/series", func(r chi.Router) {
+			r.Use(app.AuthTokenMiddleware)
+			r.Post("/", app.createSeriesHandler)
+			r.Get("/{seriesID}", app.getSeriesHandler)
+		})
 		r.Route("/users", func(r chi.Router) {
 			r.Put("/activate/{token}", app.activateUserHandler)
+			r.Get("/{userID}/avatar", app.getAvatarHandler)
+			r.Route("/me", func(r chi.Router) {
+				r.Use(app.AuthTokenMiddleware)
+				r.Patch("/", app.updateProfileHandler)
+				r.Delete("/", app.deleteAccountHandler)
+				r.Put("/avatar", app.uploadAvatarHandler)
+				r.Put("/username", app.changeUsernameHandler)
+				r.Patch("/preferences", app.updatePreferencesHandler)
+				r.Get("/settings", app.getSettingsHandler)
+				r.Patch("/settings", app.updateSettingsHandler)
+				r.Get("/analytics", app.getAnalyticsHandler)
+				r.Put("/privacy", app.updatePrivacyHandler)
+				r.Get("/follow-requests", app.getFollowRequestsHandler)
+				r.Post("/follow-requests/{userID}/approve", app.approveFollowRequestHandler)
+				r.Delete("/follow-requests/{userID}", app.rejectFollowRequestHandler)
+				r.Get("/blocks", app.getBlockedUsersHandler)
+				r.Get("/mutes", app.getMutedUsersHandler)
+				r.Post("/exports", app.requestDataExportHandler)
+				r.Get("/exports/{exportID}", app.getDataExportHandler)
+				r.Get("/suggestions", app.getSuggestionsHandler)
+				r.Delete("/suggestions/{userID}", app.dismissSuggestionHandler)
+			})
 			r.Route("/{userID}", func(r chi.Router) {
 				r.Use(app.AuthTokenMiddleware)
 				r.Get("/", app.getUserHandler)
 				r.Put("/follow", app.followUserHandler)
 				r.Put("/unfollow", app.unfollowUserHandler)
+				r.Delete("/follow-request", app.withdrawFollowRequestHandler)
+				r.Put("/block", app.blockUserHandler)
+				r.Delete("/block", app.unblockUserHandler)
+				r.Put("/mute", app.muteUserHandler)
+				r.Delete("/mute", app.unmuteUserHandler)
+				r.Get("/mentions", app.getUserMentionsHandler)
+				r.Get("/pinned", app.getPinnedPostsHandler)
+				r.Get("/posts", app.getUserPostsHandler)
+				r.Get("/followers", app.getFollowersHandler)
+				r.Get("/following", app.getFollowingHandler)
 			})
 			r.Group(func(r chi.Router) {
+				r.Use(app.AuthTokenMiddleware)
 				r.Get("/feed", app.getUserFeedHandler)
+				r.Get("/by-username/{username}", app.getUserByUsernameHandler)
+				r.Get("/search", app.searchUsersHandler)
 			})
 		})
+		r.Route("/search", func(r chi.Router) {
+			r.Use(app.AuthTokenMiddleware)
+			r.Get("/posts", app.searchPostsHandler)
+		})
+		r.Route("/tags", func(r chi.Router) {
+			r.Use(app.AuthTokenMiddleware)
+			r.Get("/trending", app.getTrendingTagsHandler)
+			r.Get("/autocomplete", app.autocompleteTagsHandler)
+			r.Get("/{tag}", app.getTagHandler)
+			r.Get("/{tag}/posts", app.getTagPostsHandler)
+			r.With(app.requireRole("admin")).Patch("/{tag}", app.renameTagHandler)
+			r.With(app.requireRole("admin")).Post("/{tag}/merge", app.mergeTagHandler)
+		})
 		// Public routes
+		r.Get("/exports/{exportID}/download", app.downloadDataExportHandler)
 		r.Route("/authentication", func(r chi.Router) {
 			r.Post("/user", app.registerUserHandler)
+			r.Post("/token", app.createTokenHandler)
 		})
 
 	})
@@ -125,6 +225,20 @@ func (app *application) mount() http.Handler {
 	return r
 }
 
+// apiBaseURL is the external URL of the versioned API, for links sent out
+// of band. EXTERNAL_URL is usually a bare host, as swagger wants it.
+func (app *application) apiBaseURL() string {
+	base := app.config.apiURL
+	if !strings.Contains(base, "://") {
+		scheme := "http"
+		if app.config.env == "production" {
+			scheme = "https"
+		}
+		base = scheme + "://" + base
+	}
+	return strings.TrimSuffix(base, "/") + "/v1"
+}
+
 func (app *application) run(mux http.Handler) error {
 	docs.SwaggerInfo.Version = version
 	docs.SwaggerInfo.Host = app.config.apiURL
@@ -3,6 +3,7 @@ package main
 import (
 	"crypto/sha256"
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"net/http"
 	"time"
@@ -13,6 +14,8 @@ import (
 	"github.com/google/uuid"
 )
 
+var errInvalidPassword = errors.New("invalid password")
+
 type RegisterUserPayload struct {
 	Username string `json:"username" validate:"required,max=100"`
 	Email    string `json:"email" validate:"required,email,max=100"`
@@ -113,23 +116,23 @@ func (app *application) registerUserHandler(w http.ResponseWriter, r *http.Reque
 }
 
 type CreateUserTokenPayload struct {
-	Email    string `json:"email validate:"required,email,max=255"`
-	Password string `json:"password validate:"required,min=3,max=72"`
+	Email    string `json:"email" validate:"required,email,max=255"`
+	Password string `json:"password" validate:"required,min=3,max=72"`
 }
 
 // createTokenHandler godoc
 //
 //	@Summary		Create a token
-//	@Description	Creates a token for a user
-//	@Tags			authrication
+//	@Description	Creates a token for a user. Logging in to an account pending deletion restores it.
+//	@Tags			authentication
 //	@Accept			json
 //	@Produce		json
-//	@Paramp			payload body CreateUserTokenPayload true "User credientials"
-//	@Success		200	{string}	string	"Token"
-//	@Failure		400	{object}	error
-//	@Failure		401	{object}	error
-//	@Failure		500	{object}	error
-//	@Router			/authenication/token [post]
+//	@Param			payload	body		CreateUserTokenPayload	true	"User credentials"
+//	@Success		201		{string}	string					"Token"
+//	@Failure		400		{object}	error
+//	@Failure		401		{object}	error
+//	@Failure		500		{object}	error
+//	@Router			/authentication/token [post]
 func (app *application) createTokenHandler(w http.ResponseWriter, r *http.Request) {
 	var payload CreateUserTokenPayload
 	if err := readJSON(w, r, &payload); err != nil {
@@ -142,7 +145,9 @@ func (app *application) createTokenHandler(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	user, err := app.store.Users.GetByEmail(r.Context(), payload.Email)
+	ctx := r.Context()
+
+	user, err := app.store.Users.GetByEmail(ctx, payload.Email)
 	if err != nil {
 		switch err {
 		case store.ErrNotFound:
@@ -153,6 +158,23 @@ func (app *application) createTokenHandler(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
+	if err := user.Password.Compare(payload.Password); err != nil {
+		app.unauthorisedError(w, r, errInvalidPassword)
+		return
+	}
+
+	if user.DeletionRequestedAt != nil {
+		if err := app.store.Users.Restore(ctx, user.ID, app.config.deletionGrace); err != nil {
+			switch err {
+			case store.ErrNotFound:
+				app.unauthorisedError(w, r, err)
+			default:
+				app.internalServerError(w, r, err)
+			}
+			return
+		}
+	}
+
 	claims := jwt.MapClaims{
 		"sub": user.ID,
 		"exp": time.Now().Add(app.config.auth.token.exp).Unix(),
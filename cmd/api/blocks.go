@@ -0,0 +1,236 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/Aiyanu/gophersocial/internal/store"
+	"github.com/go-chi/chi/v5"
+)
+
+var errBlocked = errors.New("one of the users blocks the other")
+
+type BlockListPage struct {
+	Users      []store.BlockListEntry `json:"users"`
+	NextCursor string                 `json:"next_cursor,omitempty"`
+}
+
+// BlockUser godoc
+//
+//	@Summary		Blocks a user
+//	@Description	Removes any follow or follow request between the two users, stops new ones and hides each user's posts, comments and mentions from the other
+//	@Tags			users
+//	@Accept			json
+//	@Produce		json
+//	@Param			userID	path		int		true	"User ID"
+//	@Success		204		{string}	string	"User blocked"
+//	@Failure		400		{object}	error
+//	@Failure		404		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/users/{userID}/block [put]
+func (app *application) blockUserHandler(w http.ResponseWriter, r *http.Request) {
+	user := getUserFromContext(r)
+
+	userID, ok := app.otherUserID(w, r, user)
+	if !ok {
+		return
+	}
+
+	ctx := r.Context()
+
+	if err := app.store.Blocks.Block(ctx, user.ID, userID); err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notFoundError(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	if err := app.invalidateFollowCounts(ctx, user.ID, userID); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// UnblockUser godoc
+//
+//	@Summary		Unblocks a user
+//	@Description	Lifts a block. Follows removed by the block are not restored.
+//	@Tags			users
+//	@Accept			json
+//	@Produce		json
+//	@Param			userID	path		int		true	"User ID"
+//	@Success		204		{string}	string	"User unblocked"
+//	@Failure		400		{object}	error
+//	@Failure		404		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/users/{userID}/block [delete]
+func (app *application) unblockUserHandler(w http.ResponseWriter, r *http.Request) {
+	user := getUserFromContext(r)
+
+	userID, ok := app.otherUserID(w, r, user)
+	if !ok {
+		return
+	}
+
+	app.undoRelation(w, r, app.store.Blocks.Unblock(r.Context(), user.ID, userID))
+}
+
+// MuteUser godoc
+//
+//	@Summary		Mutes a user
+//	@Description	Hides the user's posts from the authenticated user's feed without telling them or unfollowing
+//	@Tags			users
+//	@Accept			json
+//	@Produce		json
+//	@Param			userID	path		int		true	"User ID"
+//	@Success		204		{string}	string	"User muted"
+//	@Failure		400		{object}	error
+//	@Failure		404		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/users/{userID}/mute [put]
+func (app *application) muteUserHandler(w http.ResponseWriter, r *http.Request) {
+	user := getUserFromContext(r)
+
+	userID, ok := app.otherUserID(w, r, user)
+	if !ok {
+		return
+	}
+
+	if err := app.store.Mutes.Mute(r.Context(), user.ID, userID); err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notFoundError(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// UnmuteUser godoc
+//
+//	@Summary		Unmutes a user
+//	@Description	Shows the user's posts in the authenticated user's feed again
+//	@Tags			users
+//	@Accept			json
+//	@Produce		json
+//	@Param			userID	path		int		true	"User ID"
+//	@Success		204		{string}	string	"User unmuted"
+//	@Failure		400		{object}	error
+//	@Failure		404		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/users/{userID}/mute [delete]
+func (app *application) unmuteUserHandler(w http.ResponseWriter, r *http.Request) {
+	user := getUserFromContext(r)
+
+	userID, ok := app.otherUserID(w, r, user)
+	if !ok {
+		return
+	}
+
+	app.undoRelation(w, r, app.store.Mutes.Unmute(r.Context(), user.ID, userID))
+}
+
+// GetBlockedUsers godoc
+//
+//	@Summary		Fetches blocked users
+//	@Description	Fetches the accounts the authenticated user blocks, most recent first
+//	@Tags			users
+//	@Accept			json
+//	@Produce		json
+//	@Param			limit	query		int		false	"Page size (max 50)"
+//	@Param			cursor	query		string	false	"Cursor from the previous page"
+//	@Success		200		{object}	BlockListPage
+//	@Failure		400		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/users/me/blocks [get]
+func (app *application) getBlockedUsersHandler(w http.ResponseWriter, r *http.Request) {
+	app.blockList(w, r, app.store.Blocks.GetBlocked)
+}
+
+// GetMutedUsers godoc
+//
+//	@Summary		Fetches muted users
+//	@Description	Fetches the accounts the authenticated user mutes, most recent first
+//	@Tags			users
+//	@Accept			json
+//	@Produce		json
+//	@Param			limit	query		int		false	"Page size (max 50)"
+//	@Param			cursor	query		string	false	"Cursor from the previous page"
+//	@Success		200		{object}	BlockListPage
+//	@Failure		400		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/users/me/mutes [get]
+func (app *application) getMutedUsersHandler(w http.ResponseWriter, r *http.Request) {
+	app.blockList(w, r, app.store.Mutes.GetMuted)
+}
+
+type blockListFunc func(ctx context.Context, userID int64, cursor string, limit int) ([]store.BlockListEntry, string, error)
+
+func (app *application) blockList(w http.ResponseWriter, r *http.Request, list blockListFunc) {
+	user := getUserFromContext(r)
+
+	limit, err := parseLimit(r, 20, 50)
+	if err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	users, next, err := list(r.Context(), user.ID, r.URL.Query().Get("cursor"), limit)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrInvalidCursor):
+			app.badRequestError(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, BlockListPage{Users: users, NextCursor: next}); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// otherUserID reads the userID path parameter, refusing the user's own ID.
+func (app *application) otherUserID(w http.ResponseWriter, r *http.Request, user *store.User) (int64, bool) {
+	userID, err := strconv.ParseInt(chi.URLParam(r, "userID"), 10, 64)
+	if err != nil {
+		app.badRequestError(w, r, err)
+		return 0, false
+	}
+	if userID == user.ID {
+		app.badRequestError(w, r, errors.New("cannot target yourself"))
+		return 0, false
+	}
+	return userID, true
+}
+
+func (app *application) undoRelation(w http.ResponseWriter, r *http.Request, err error) {
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notFoundError(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/Aiyanu/gophersocial/internal/markdown"
+	"github.com/Aiyanu/gophersocial/internal/store"
+)
+
+type CreateCommentPayload struct {
+	Content string `json:"content" validate:"required,max=1000"`
+}
+
+// CreateComment godoc
+//
+//	@Summary		Creates a comment
+//	@Description	Creates a comment on a post
+//	@Tags			posts
+//	@Accept			json
+//	@Produce		json
+//	@Param			id		path		int						true	"Post ID"
+//	@Param			payload	body		CreateCommentPayload	true	"Comment payload"
+//	@Success		201		{object}	store.Comment
+//	@Failure		400		{object}	error
+//	@Failure		401		{object}	error
+//	@Failure		403		{object}	error
+//	@Failure		404		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/posts/{id}/comments [post]
+func (app *application) createCommentHandler(w http.ResponseWriter, r *http.Request) {
+	post := getPostFromCtx(r)
+	user := getUserFromContext(r)
+
+	var payload CreateCommentPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	ctx := r.Context()
+
+	blocked, err := app.store.Blocks.IsBlocked(ctx, user.ID, post.UserID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+	if blocked {
+		app.forbiddenError(w, r, errBlocked)
+		return
+	}
+
+	contentHTML, err := markdown.Render(payload.Content)
+	if err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	comment := &store.Comment{
+		PostID:      post.ID,
+		UserID:      user.ID,
+		Content:     payload.Content,
+		ContentHTML: contentHTML,
+		User:        *user,
+		Mentions:    markdown.Mentions(payload.Content),
+		Hashtags:    markdown.Hashtags(payload.Content),
+	}
+
+	if err := app.store.Comments.Create(ctx, comment); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusCreated, comment); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
@@ -2,6 +2,8 @@ package main
 
 import (
 	"net/http"
+	"strconv"
+	"time"
 )
 
 func (app *application) internalServerError(w http.ResponseWriter, r *http.Request, err error) {
@@ -33,3 +35,23 @@ func (app *application) unauthorisedBasicError(w http.ResponseWriter, r *http.Re
 	w.Header().Set("WWW-Authenticate", `Basic realm="restricted", charset="UTF-8"`)
 	writeJSONError(w, http.StatusUnauthorized, "unauthorized")
 }
+
+func (app *application) tooManyRequestsError(w http.ResponseWriter, r *http.Request, err error, retryAfter time.Duration) {
+	app.logger.Warnw("Too many requests", "method", r.Method, "path", r.URL.Path, "error", err)
+	w.Header().Set("Retry-After", strconv.Itoa(int(retryAfter.Seconds())+1))
+	writeJSONError(w, http.StatusTooManyRequests, err.Error())
+}
+
+func (app *application) preconditionRequiredError(w http.ResponseWriter, r *http.Request, err error) {
+	app.logger.Warnw("Precondition required", "method", r.Method, "path", r.URL.Path, "error", err)
+	writeJSONError(w, http.StatusPreconditionRequired, err.Error())
+}
+
+// preconditionFailedError answers with the current representation so the
+// client can reapply its change on top of it.
+func (app *application) preconditionFailedError(w http.ResponseWriter, r *http.Request, err error, current any) {
+	app.logger.Warnw("Precondition failed", "method", r.Method, "path", r.URL.Path, "error", err)
+	if err := app.jsonResponse(w, http.StatusPreconditionFailed, current); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/Aiyanu/gophersocial/internal/store"
+)
+
+var errExpiryInPast = errors.New("expiry must be in the future")
+
+type SetExpiryPayload struct {
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+// SetPostExpiry godoc
+//
+//	@Summary		Sets when a post expires
+//	@Description	Extends, shortens or, with a null expires_at, removes the expiry of one of the user's posts
+//	@Tags			posts
+//	@Accept			json
+//	@Produce		json
+//	@Param			id		path		int					true	"Post ID"
+//	@Param			payload	body		SetExpiryPayload	true	"Expiry payload"
+//	@Success		204		{string}	string				"Expiry updated"
+//	@Failure		400		{object}	error
+//	@Failure		403		{object}	error
+//	@Failure		404		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/posts/{id}/expiry [put]
+func (app *application) setPostExpiryHandler(w http.ResponseWriter, r *http.Request) {
+	post := getPostFromCtx(r)
+	user := getUserFromContext(r)
+
+	if post.UserID != user.ID {
+		app.forbiddenError(w, r, errors.New("only the author can change when a post expires"))
+		return
+	}
+
+	var payload SetExpiryPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	if payload.ExpiresAt != nil && !payload.ExpiresAt.After(time.Now()) {
+		app.badRequestError(w, r, errExpiryInPast)
+		return
+	}
+
+	if err := app.store.Posts.SetExpiry(r.Context(), post.ID, payload.ExpiresAt); err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notFoundError(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
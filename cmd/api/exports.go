@@ -0,0 +1,219 @@
+package main
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Aiyanu/gophersocial/internal/export"
+	"github.com/Aiyanu/gophersocial/internal/mailer"
+	"github.com/Aiyanu/gophersocial/internal/store"
+	"github.com/go-chi/chi/v5"
+)
+
+var errInvalidSignature = errors.New("download link is invalid or has expired")
+
+// RequestDataExport godoc
+//
+//	@Summary		Requests a data export
+//	@Description	Queues an archive of everything held about the authenticated user. The user is emailed when it is ready.
+//	@Tags			users
+//	@Accept			json
+//	@Produce		json
+//	@Success		202	{object}	store.DataExport
+//	@Failure		409	{object}	error	"An export is already in progress"
+//	@Failure		500	{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/users/me/exports [post]
+func (app *application) requestDataExportHandler(w http.ResponseWriter, r *http.Request) {
+	user := getUserFromContext(r)
+
+	exp, err := app.store.Exports.Create(r.Context(), user.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrConflict):
+			app.conflictError(w, r, errors.New("an export is already in progress"))
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusAccepted, exp); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// GetDataExport godoc
+//
+//	@Summary		Fetches a data export
+//	@Description	Fetches the status of one of the user's exports, with a signed download link once it is ready
+//	@Tags			users
+//	@Accept			json
+//	@Produce		json
+//	@Param			exportID	path		int	true	"Export ID"
+//	@Success		200			{object}	store.DataExport
+//	@Failure		404			{object}	error
+//	@Failure		500			{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/users/me/exports/{exportID} [get]
+func (app *application) getDataExportHandler(w http.ResponseWriter, r *http.Request) {
+	user := getUserFromContext(r)
+
+	id, err := strconv.ParseInt(chi.URLParam(r, "exportID"), 10, 64)
+	if err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	exp, err := app.store.Exports.GetByID(r.Context(), id, user.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notFoundError(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	if exp.Status == store.ExportReady && exp.ExpiresAt.After(time.Now()) {
+		exp.DownloadURL = "/v1" + app.exportDownloadPath(exp)
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, exp); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// DownloadDataExport godoc
+//
+//	@Summary		Downloads a data export
+//	@Description	Serves the zip archive of an export. The link is signed and stops working when the export expires.
+//	@Tags			users
+//	@Produce		application/zip
+//	@Param			exportID	path		int		true	"Export ID"
+//	@Param			expires		query		int		true	"Expiry as a Unix timestamp"
+//	@Param			signature	query		string	true	"Link signature"
+//	@Success		200			{file}		file
+//	@Failure		403			{object}	error
+//	@Failure		404			{object}	error
+//	@Failure		500			{object}	error
+//	@Router			/exports/{exportID}/download [get]
+func (app *application) downloadDataExportHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "exportID"), 10, 64)
+	if err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	expires, err := strconv.ParseInt(r.URL.Query().Get("expires"), 10, 64)
+	if err != nil || time.Now().Unix() > expires {
+		app.forbiddenError(w, r, errInvalidSignature)
+		return
+	}
+
+	want := app.exportSignature(id, expires)
+	if !hmac.Equal([]byte(want), []byte(r.URL.Query().Get("signature"))) {
+		app.forbiddenError(w, r, errInvalidSignature)
+		return
+	}
+
+	archive, err := app.store.Exports.GetArchive(r.Context(), id)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notFoundError(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="gophersocial-export-%d.zip"`, id))
+	w.Header().Set("Content-Length", strconv.Itoa(len(archive)))
+	w.Header().Set("Cache-Control", "private, no-store")
+	w.Write(archive)
+}
+
+// processDataExports builds the archives of queued exports one at a time
+// and emails their owners. Archives past their expiry are dropped first.
+func (app *application) processDataExports(ctx context.Context) error {
+	if err := app.store.Exports.DeleteExpired(ctx); err != nil {
+		return err
+	}
+
+	for ctx.Err() == nil {
+		exp, err := app.store.Exports.Claim(ctx)
+		if err != nil {
+			if errors.Is(err, store.ErrNotFound) {
+				return nil
+			}
+			return err
+		}
+
+		if err := app.buildDataExport(ctx, exp); err != nil {
+			app.logger.Errorw("data export failed", "export_id", exp.ID, "error", err)
+			if err := app.store.Exports.Fail(ctx, exp.ID); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (app *application) buildDataExport(ctx context.Context, exp *store.DataExport) error {
+	data, err := app.store.Exports.Collect(ctx, exp.UserID)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	archive, err := export.Archive(data, now)
+	if err != nil {
+		return err
+	}
+
+	if err := app.store.Exports.Complete(ctx, exp, archive, now.Add(app.config.exportTTL)); err != nil {
+		return err
+	}
+
+	user := data.Profile
+	vars := struct {
+		Username    string
+		DownloadURL string
+		ExpiresAt   string
+	}{
+		Username:    user.Username,
+		DownloadURL: app.apiBaseURL() + app.exportDownloadPath(exp),
+		ExpiresAt:   exp.ExpiresAt.Format("January 2, 2006"),
+	}
+
+	// The archive is ready either way and its status endpoint hands out
+	// the link too.
+	isProdEnv := app.config.env == "production"
+	if _, err := app.mailer.Send(mailer.DataExportTemplate, user.Username, user.Email, vars, !isProdEnv); err != nil {
+		app.logger.Errorw("error sending data export email", "export_id", exp.ID, "error", err)
+	}
+	return nil
+}
+
+// exportDownloadPath is the signed download path of a ready export, valid
+// until the export expires.
+func (app *application) exportDownloadPath(exp *store.DataExport) string {
+	expires := exp.ExpiresAt.Unix()
+	return fmt.Sprintf("/exports/%d/download?expires=%d&signature=%s", exp.ID, expires, app.exportSignature(exp.ID, expires))
+}
+
+func (app *application) exportSignature(id, expires int64) string {
+	mac := hmac.New(sha256.New, []byte(app.config.auth.token.secret))
+	fmt.Fprintf(mac, "export:%d:%d", id, expires)
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -3,6 +3,7 @@ package main
 import (
 	"net/http"
 
+	"github.com/Aiyanu/gophersocial/internal/analytics"
 	"github.com/Aiyanu/gophersocial/internal/store"
 )
 
@@ -17,7 +18,7 @@ import (
 //	@Param			until	query		string	false	"Until"
 //	@Param			limit	query		int		false	"Limit"
 //	@Param			offset	query		int		false	"Offset"
-//	@Param			sort	query		string	false	"Sort"
+//	@Param			sort	query		string	false	"Sort, defaulting to the user's feed_sort setting"
 //	@Param			tags	query		string	false	"Tags"
 //	@Param			search	query		string	false	"Search"
 //	@Success		200		{object}	[]store.PostWithMetadata
@@ -26,10 +27,19 @@ import (
 //	@Security		ApiKeyAuth
 //	@Router			/users/feed [get]
 func (app *application) getUserFeedHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	user := getUserFromContext(r)
+
+	settings, err := app.getSettings(ctx, user.ID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
 	defaultFQ := store.PaginatedFeedQuery{
 		Limit:  20,
 		Offset: 0,
-		Sort:   "desc",
+		Sort:   settings.FeedSort,
 	}
 
 	fq, err := defaultFQ.Parse(r)
@@ -43,13 +53,27 @@ func (app *application) getUserFeedHandler(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	ctx := r.Context()
-
-	feed, err := app.store.Posts.GetUserFeed(ctx, int64(1), fq)
+	feed, err := app.store.Posts.GetUserFeed(ctx, user.ID, fq)
 	if err != nil {
 		app.internalServerError(w, r, err)
 		return
 	}
+
+	if err := app.attachPolls(ctx, feed, user.ID); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+	if err := app.attachLinkPreviews(ctx, feed); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	for i := range feed {
+		applySensitivePreference(user.ID, settings, &feed[i].Post, false)
+		if feed[i].UserID != user.ID {
+			app.postStats.Record(analytics.Impression, feed[i].ID, user.ID)
+		}
+	}
 	if err := app.jsonResponse(w, http.StatusOK, feed); err != nil {
 		app.internalServerError(w, r, err)
 	}
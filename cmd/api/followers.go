@@ -0,0 +1,94 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/Aiyanu/gophersocial/internal/store"
+	"github.com/go-chi/chi/v5"
+)
+
+type FollowListPage struct {
+	Users      []store.FollowListEntry `json:"users"`
+	NextCursor string                  `json:"next_cursor,omitempty"`
+}
+
+// GetFollowers godoc
+//
+//	@Summary		Fetches a user's followers
+//	@Description	Fetches the accounts following a user, most recent first, flagged with whether they follow the viewer and whether the viewer follows them
+//	@Tags			users
+//	@Produce		json
+//	@Param			userID	path		int		true	"User ID"
+//	@Param			limit	query		int		false	"Limit, up to 50"
+//	@Param			cursor	query		string	false	"Cursor returned by the previous page"
+//	@Success		200		{object}	FollowListPage
+//	@Failure		400		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/users/{userID}/followers [get]
+func (app *application) getFollowersHandler(w http.ResponseWriter, r *http.Request) {
+	app.followList(w, r, app.store.Followers.GetFollowers)
+}
+
+// GetFollowing godoc
+//
+//	@Summary		Fetches the accounts a user follows
+//	@Description	Fetches the accounts a user follows, most recently followed first, flagged with whether they follow the viewer and whether the viewer follows them
+//	@Tags			users
+//	@Produce		json
+//	@Param			userID	path		int		true	"User ID"
+//	@Param			limit	query		int		false	"Limit, up to 50"
+//	@Param			cursor	query		string	false	"Cursor returned by the previous page"
+//	@Success		200		{object}	FollowListPage
+//	@Failure		400		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/users/{userID}/following [get]
+func (app *application) getFollowingHandler(w http.ResponseWriter, r *http.Request) {
+	app.followList(w, r, app.store.Followers.GetFollowing)
+}
+
+type followListFunc func(ctx context.Context, userID, viewerID int64, cursor string, limit int) ([]store.FollowListEntry, string, error)
+
+func (app *application) followList(w http.ResponseWriter, r *http.Request, list followListFunc) {
+	userID, err := strconv.ParseInt(chi.URLParam(r, "userID"), 10, 64)
+	if err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	limit, err := parseLimit(r, 20, 50)
+	if err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	viewer := getUserFromContext(r)
+
+	users, next, err := list(r.Context(), userID, viewer.ID, r.URL.Query().Get("cursor"), limit)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrInvalidCursor):
+			app.badRequestError(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, FollowListPage{Users: users, NextCursor: next}); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// invalidateFollowCounts drops the cached profiles of both sides of a
+// follow so their counters are read fresh.
+func (app *application) invalidateFollowCounts(ctx context.Context, followerID, userID int64) error {
+	if err := app.cacheStorage.Users.Delete(ctx, followerID); err != nil {
+		return err
+	}
+	return app.cacheStorage.Users.Delete(ctx, userID)
+}
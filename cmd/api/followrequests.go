@@ -0,0 +1,245 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/Aiyanu/gophersocial/internal/store"
+	"github.com/go-chi/chi/v5"
+)
+
+type FollowRequestStatus struct {
+	Status string `json:"status"`
+}
+
+type FollowRequestsPage struct {
+	Requests   []store.FollowRequest `json:"requests"`
+	NextCursor string                `json:"next_cursor,omitempty"`
+}
+
+type UpdatePrivacyPayload struct {
+	IsPrivate *bool `json:"is_private" validate:"required"`
+}
+
+// requestFollow answers a follow of a private account by leaving a pending
+// request for its owner to approve.
+func (app *application) requestFollow(w http.ResponseWriter, r *http.Request, requesterID, userID int64) {
+	if err := app.store.FollowRequests.Create(r.Context(), requesterID, userID); err != nil {
+		switch {
+		case errors.Is(err, store.ErrConflict):
+			app.conflictError(w, r, err)
+		case errors.Is(err, store.ErrNotFound):
+			app.notFoundError(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusAccepted, FollowRequestStatus{Status: "pending"}); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// UpdatePrivacy godoc
+//
+//	@Summary		Makes the user's account private or public
+//	@Description	While private, follows become requests the user has to approve. Making the account public approves every pending request.
+//	@Tags			users
+//	@Accept			json
+//	@Produce		json
+//	@Param			payload	body		UpdatePrivacyPayload	true	"Privacy payload"
+//	@Success		200		{object}	store.User
+//	@Failure		400		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/users/me/privacy [put]
+func (app *application) updatePrivacyHandler(w http.ResponseWriter, r *http.Request) {
+	user := getUserFromContext(r)
+
+	var payload UpdatePrivacyPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	ctx := r.Context()
+
+	approved, err := app.store.Users.SetPrivate(ctx, user.ID, *payload.IsPrivate)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	for _, requesterID := range approved {
+		if err := app.cacheStorage.Users.Delete(ctx, requesterID); err != nil {
+			app.internalServerError(w, r, err)
+			return
+		}
+	}
+	if err := app.cacheStorage.Users.Delete(ctx, user.ID); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	user.IsPrivate = *payload.IsPrivate
+	user.FollowersCount += len(approved)
+
+	if err := app.jsonResponse(w, http.StatusOK, user); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// GetFollowRequests godoc
+//
+//	@Summary		Fetches pending follow requests
+//	@Description	Fetches the accounts waiting for the user to approve their follow, most recent first
+//	@Tags			users
+//	@Accept			json
+//	@Produce		json
+//	@Param			limit	query		int		false	"Page size (max 50)"
+//	@Param			cursor	query		string	false	"Cursor from the previous page"
+//	@Success		200		{object}	FollowRequestsPage
+//	@Failure		400		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/users/me/follow-requests [get]
+func (app *application) getFollowRequestsHandler(w http.ResponseWriter, r *http.Request) {
+	user := getUserFromContext(r)
+
+	limit, err := parseLimit(r, 20, 50)
+	if err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	requests, next, err := app.store.FollowRequests.GetIncoming(r.Context(), user.ID, r.URL.Query().Get("cursor"), limit)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrInvalidCursor):
+			app.badRequestError(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, FollowRequestsPage{Requests: requests, NextCursor: next}); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// ApproveFollowRequest godoc
+//
+//	@Summary		Approves a follow request
+//	@Description	Lets the requesting account follow the user
+//	@Tags			users
+//	@Accept			json
+//	@Produce		json
+//	@Param			userID	path		int		true	"Requester ID"
+//	@Success		204		{string}	string	"Request approved"
+//	@Failure		400		{object}	error
+//	@Failure		404		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/users/me/follow-requests/{userID}/approve [post]
+func (app *application) approveFollowRequestHandler(w http.ResponseWriter, r *http.Request) {
+	user := getUserFromContext(r)
+
+	requesterID, err := strconv.ParseInt(chi.URLParam(r, "userID"), 10, 64)
+	if err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	ctx := r.Context()
+
+	if err := app.store.FollowRequests.Approve(ctx, user.ID, requesterID); err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notFoundError(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	if err := app.invalidateFollowCounts(ctx, requesterID, user.ID); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// RejectFollowRequest godoc
+//
+//	@Summary		Rejects a follow request
+//	@Description	Drops a pending request to follow the user
+//	@Tags			users
+//	@Accept			json
+//	@Produce		json
+//	@Param			userID	path		int		true	"Requester ID"
+//	@Success		204		{string}	string	"Request rejected"
+//	@Failure		400		{object}	error
+//	@Failure		404		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/users/me/follow-requests/{userID} [delete]
+func (app *application) rejectFollowRequestHandler(w http.ResponseWriter, r *http.Request) {
+	user := getUserFromContext(r)
+
+	requesterID, err := strconv.ParseInt(chi.URLParam(r, "userID"), 10, 64)
+	if err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	app.deleteFollowRequest(w, r, user.ID, requesterID)
+}
+
+// WithdrawFollowRequest godoc
+//
+//	@Summary		Withdraws a follow request
+//	@Description	Cancels the user's pending request to follow a private account
+//	@Tags			users
+//	@Accept			json
+//	@Produce		json
+//	@Param			userID	path		int		true	"User ID"
+//	@Success		204		{string}	string	"Request withdrawn"
+//	@Failure		400		{object}	error
+//	@Failure		404		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/users/{userID}/follow-request [delete]
+func (app *application) withdrawFollowRequestHandler(w http.ResponseWriter, r *http.Request) {
+	user := getUserFromContext(r)
+
+	userID, err := strconv.ParseInt(chi.URLParam(r, "userID"), 10, 64)
+	if err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	app.deleteFollowRequest(w, r, userID, user.ID)
+}
+
+func (app *application) deleteFollowRequest(w http.ResponseWriter, r *http.Request, userID, requesterID int64) {
+	if err := app.store.FollowRequests.Delete(r.Context(), userID, requesterID); err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notFoundError(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
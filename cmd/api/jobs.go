@@ -0,0 +1,37 @@
+package main
+
+import (
+	"context"
+	"time"
+)
+
+// startJobs launches the background jobs of the API. They stop when ctx is
+// cancelled.
+func (app *application) startJobs(ctx context.Context) {
+	go app.runPeriodically(ctx, "refresh trending tags", app.config.jobs.trendingInterval, app.store.Tags.RefreshTrending)
+	go app.runPeriodically(ctx, "flush post stats", app.config.jobs.statsFlushInterval, app.flushPostStats)
+	go app.runPeriodically(ctx, "unfurl links", app.config.jobs.unfurlInterval, app.unfurlLinks)
+	go app.runPeriodically(ctx, "delete expired posts", app.config.jobs.expiryInterval, app.store.Posts.DeleteExpired)
+	go app.runPeriodically(ctx, "purge deleted accounts", app.config.jobs.purgeInterval, app.purgeDeletedAccounts)
+	go app.runPeriodically(ctx, "build data exports", app.config.jobs.exportInterval, app.processDataExports)
+	go app.runPeriodically(ctx, "refresh follow suggestions", app.config.jobs.suggestionInterval, app.store.Suggestions.Refresh)
+}
+
+// runPeriodically runs fn right away and then every interval until ctx is
+// done. Failures are logged and retried on the next tick.
+func (app *application) runPeriodically(ctx context.Context, name string, interval time.Duration, fn func(context.Context) error) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := fn(ctx); err != nil {
+			app.logger.Errorw("background job failed", "job", name, "error", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
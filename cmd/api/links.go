@@ -0,0 +1,60 @@
+package main
+
+import (
+	"context"
+
+	"github.com/Aiyanu/gophersocial/internal/store"
+)
+
+// unfurlBatchSize bounds how many URLs one run of the unfurl job fetches.
+const unfurlBatchSize = 20
+
+// unfurlLinks fetches the previews of newly linked URLs and of cached
+// previews that expired. A URL that cannot be unfurled is recorded as failed
+// so it is not retried until its cache entry expires.
+func (app *application) unfurlLinks(ctx context.Context) error {
+	urls, err := app.store.LinkPreviews.Due(ctx, app.config.unfurl.ttl, unfurlBatchSize)
+	if err != nil {
+		return err
+	}
+
+	for _, url := range urls {
+		var preview *store.LinkPreview
+		p, err := app.unfurler.Fetch(ctx, url)
+		if err != nil {
+			app.logger.Infow("link preview failed", "url", url, "error", err)
+		} else {
+			preview = &store.LinkPreview{
+				URL:         url,
+				Title:       p.Title,
+				Description: p.Description,
+				ImageURL:    p.ImageURL,
+				SiteName:    p.SiteName,
+			}
+		}
+
+		if err := app.store.LinkPreviews.Save(ctx, url, preview); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// attachLinkPreviews loads the link previews for a page of posts in one
+// round trip.
+func (app *application) attachLinkPreviews(ctx context.Context, posts []store.PostWithMetadata) error {
+	ids := make([]int64, len(posts))
+	for i, p := range posts {
+		ids[i] = p.ID
+	}
+
+	previews, err := app.store.LinkPreviews.GetByPostIDs(ctx, ids)
+	if err != nil {
+		return err
+	}
+
+	for i := range posts {
+		posts[i].LinkPreviews = previews[posts[i].ID]
+	}
+	return nil
+}
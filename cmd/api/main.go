@@ -1,15 +1,18 @@
 package main
 
 import (
+	"context"
 	"log"
 	"time"
 
+	"github.com/Aiyanu/gophersocial/internal/analytics"
 	"github.com/Aiyanu/gophersocial/internal/auth"
 	"github.com/Aiyanu/gophersocial/internal/db"
 	"github.com/Aiyanu/gophersocial/internal/env"
 	"github.com/Aiyanu/gophersocial/internal/mailer"
 	"github.com/Aiyanu/gophersocial/internal/store"
 	"github.com/Aiyanu/gophersocial/internal/store/cache"
+	"github.com/Aiyanu/gophersocial/internal/unfurl"
 	"github.com/joho/godotenv"
 	"github.com/redis/go-redis/v9"
 	"go.uber.org/zap"
@@ -79,6 +82,26 @@ func main() {
 				iss:    "gophersocial",
 			},
 		},
+		jobs: jobsConfig{
+			trendingInterval:   time.Minute * 5,
+			statsFlushInterval: time.Minute,
+			unfurlInterval:     time.Second * 30,
+			expiryInterval:     time.Minute,
+			purgeInterval:      time.Hour,
+			exportInterval:     time.Minute,
+			suggestionInterval: time.Hour * 6,
+		},
+		unfurl: unfurlConfig{
+			timeout:  time.Second * 5,
+			maxBytes: 512 << 10,
+			ttl:      time.Hour * 24 * 7,
+		},
+		maxPinnedPosts:   env.GetInt("MAX_PINNED_POSTS", 3),
+		viewDedupWindow:  time.Minute * 30,
+		usernameCooldown: time.Hour * 24 * 30,
+		usernameGrace:    time.Hour * 24 * 90,
+		deletionGrace:    time.Hour * 24 * time.Duration(env.GetInt("ACCOUNT_DELETION_GRACE_DAYS", 30)),
+		exportTTL:        time.Hour * 24 * 7,
 	}
 
 	//Logger
@@ -122,8 +145,14 @@ func main() {
 		logger:        logger,
 		mailer:        mailer,
 		authenticator: jwtAuthenicator,
+		postStats:     analytics.NewCounter(cfg.viewDedupWindow),
+		unfurler:      unfurl.New(cfg.unfurl.timeout, cfg.unfurl.maxBytes),
 	}
 
+	jobsCtx, stopJobs := context.WithCancel(context.Background())
+	defer stopJobs()
+	app.startJobs(jobsCtx)
+
 	// Start the server
 	mux := app.mount()
 	log.Printf("Starting server on %s in %s mode\n", cfg.addr, cfg.env)
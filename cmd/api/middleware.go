@@ -110,6 +110,28 @@ func (app *application) checkPostOwnership(requiredRole string, next http.Handle
 	})
 }
 
+// requireRole only lets through users whose role is at least roleName.
+func (app *application) requireRole(roleName string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			user := getUserFromContext(r)
+
+			allowed, err := app.checkRolePrecedence(r.Context(), user, roleName)
+			if err != nil {
+				app.internalServerError(w, r, err)
+				return
+			}
+
+			if !allowed {
+				app.forbiddenError(w, r, fmt.Errorf("requires the %s role", roleName))
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
 func (app *application) checkRolePrecedence(ctx context.Context, user *store.User, roleName string) (bool, error) {
 	app.logger.Infow("Cache Hit", "key", "user", "id", user.ID)
 	role, err := app.store.Roles.GetByName(ctx, roleName)
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/Aiyanu/gophersocial/internal/store"
+	"github.com/go-chi/chi/v5"
+)
+
+// PinPost godoc
+//
+//	@Summary		Pins a post
+//	@Description	Pins one of the user's public posts to the top of their profile
+//	@Tags			posts
+//	@Produce		json
+//	@Param			id	path		int		true	"Post ID"
+//	@Success		204	{string}	string	"Post pinned"
+//	@Failure		400	{object}	error
+//	@Failure		403	{object}	error
+//	@Failure		409	{object}	error
+//	@Failure		500	{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/posts/{id}/pin [put]
+func (app *application) pinPostHandler(w http.ResponseWriter, r *http.Request) {
+	post := getPostFromCtx(r)
+	user := getUserFromContext(r)
+
+	if post.UserID != user.ID {
+		app.forbiddenError(w, r, errors.New("only the author can pin a post"))
+		return
+	}
+
+	if post.Visibility != store.VisibilityPublic {
+		app.badRequestError(w, r, errors.New("only public posts can be pinned"))
+		return
+	}
+
+	err := app.store.Pins.Pin(r.Context(), user.ID, post.ID, app.config.maxPinnedPosts)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrPinLimit):
+			app.conflictError(w, r, err)
+		case errors.Is(err, store.ErrConflict):
+			app.conflictError(w, r, errors.New("post is already pinned"))
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// UnpinPost godoc
+//
+//	@Summary		Unpins a post
+//	@Description	Removes a post from the user's pinned posts
+//	@Tags			posts
+//	@Produce		json
+//	@Param			id	path		int		true	"Post ID"
+//	@Success		204	{string}	string	"Post unpinned"
+//	@Failure		403	{object}	error
+//	@Failure		404	{object}	error
+//	@Failure		500	{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/posts/{id}/pin [delete]
+func (app *application) unpinPostHandler(w http.ResponseWriter, r *http.Request) {
+	post := getPostFromCtx(r)
+	user := getUserFromContext(r)
+
+	if post.UserID != user.ID {
+		app.forbiddenError(w, r, errors.New("only the author can unpin a post"))
+		return
+	}
+
+	if err := app.store.Pins.Unpin(r.Context(), user.ID, post.ID); err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notFoundError(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetPinnedPosts godoc
+//
+//	@Summary		Fetches a user's pinned posts
+//	@Description	Fetches the posts a user pinned to their profile in pin order
+//	@Tags			users
+//	@Produce		json
+//	@Param			userID	path		int	true	"User ID"
+//	@Success		200		{object}	[]store.PostWithMetadata
+//	@Failure		400		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/users/{userID}/pinned [get]
+func (app *application) getPinnedPostsHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := strconv.ParseInt(chi.URLParam(r, "userID"), 10, 64)
+	if err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	viewer := getUserFromContext(r)
+
+	posts, err := app.store.Pins.GetByUserID(r.Context(), userID, viewer.ID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, posts); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
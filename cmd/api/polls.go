@@ -0,0 +1,158 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/Aiyanu/gophersocial/internal/store"
+)
+
+type CreatePollPayload struct {
+	Options        []string  `json:"options" validate:"required,min=2,max=10,dive,required,max=100"`
+	MultipleChoice bool      `json:"multiple_choice"`
+	HideResults    bool      `json:"hide_results"`
+	ClosesAt       time.Time `json:"closes_at" validate:"required"`
+}
+
+func (p *CreatePollPayload) toPoll() (*store.Poll, error) {
+	if !p.ClosesAt.After(time.Now()) {
+		return nil, fmt.Errorf("poll closing time must be in the future")
+	}
+
+	poll := &store.Poll{
+		MultipleChoice: p.MultipleChoice,
+		HideResults:    p.HideResults,
+		ClosesAt:       p.ClosesAt,
+	}
+	for _, text := range p.Options {
+		poll.Options = append(poll.Options, store.PollOption{Text: text})
+	}
+	return poll, nil
+}
+
+type VotePollPayload struct {
+	OptionIDs []int64 `json:"option_ids" validate:"required,min=1,max=10,unique"`
+}
+
+// VotePoll godoc
+//
+//	@Summary		Votes on a poll
+//	@Description	Casts the user's single ballot on the poll attached to a post
+//	@Tags			posts
+//	@Accept			json
+//	@Produce		json
+//	@Param			id		path		int				true	"Post ID"
+//	@Param			payload	body		VotePollPayload	true	"Vote payload"
+//	@Success		201		{object}	store.Poll
+//	@Failure		400		{object}	error
+//	@Failure		404		{object}	error
+//	@Failure		409		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/posts/{id}/poll/votes [post]
+func (app *application) votePollHandler(w http.ResponseWriter, r *http.Request) {
+	post := getPostFromCtx(r)
+	user := getUserFromContext(r)
+
+	var payload VotePollPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	ctx := r.Context()
+
+	poll, err := app.store.Polls.GetByPostID(ctx, post.ID, user.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notFoundError(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	if err := app.store.Polls.Vote(ctx, poll.ID, user.ID, payload.OptionIDs); err != nil {
+		switch {
+		case errors.Is(err, store.ErrConflict):
+			app.conflictError(w, r, errors.New("already voted on this poll"))
+		case errors.Is(err, store.ErrPollClosed), errors.Is(err, store.ErrInvalidVote):
+			app.badRequestError(w, r, err)
+		case errors.Is(err, store.ErrNotFound):
+			app.notFoundError(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	poll, err = app.store.Polls.GetByPostID(ctx, post.ID, user.ID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusCreated, poll); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// GetPollResults godoc
+//
+//	@Summary		Fetches poll results
+//	@Description	Fetches the poll attached to a post with vote counts, unless results are hidden until it closes
+//	@Tags			posts
+//	@Accept			json
+//	@Produce		json
+//	@Param			id	path		int	true	"Post ID"
+//	@Success		200	{object}	store.Poll
+//	@Failure		404	{object}	error
+//	@Failure		500	{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/posts/{id}/poll [get]
+func (app *application) getPollResultsHandler(w http.ResponseWriter, r *http.Request) {
+	post := getPostFromCtx(r)
+	user := getUserFromContext(r)
+
+	poll, err := app.store.Polls.GetByPostID(r.Context(), post.ID, user.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notFoundError(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, poll); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// attachPolls loads the polls for a page of posts in one round trip.
+func (app *application) attachPolls(ctx context.Context, posts []store.PostWithMetadata, viewerID int64) error {
+	ids := make([]int64, len(posts))
+	for i, p := range posts {
+		ids[i] = p.ID
+	}
+
+	polls, err := app.store.Polls.GetByPostIDs(ctx, ids, viewerID)
+	if err != nil {
+		return err
+	}
+
+	for i := range posts {
+		posts[i].Poll = polls[posts[i].ID]
+	}
+	return nil
+}
@@ -3,10 +3,16 @@ package main
 import (
 	"context"
 	"errors"
+	"fmt"
 	"log"
 	"net/http"
+	"slices"
 	"strconv"
+	"strings"
+	"time"
 
+	"github.com/Aiyanu/gophersocial/internal/analytics"
+	"github.com/Aiyanu/gophersocial/internal/markdown"
 	"github.com/Aiyanu/gophersocial/internal/store"
 	"github.com/go-chi/chi/v5"
 )
@@ -16,9 +22,13 @@ type postKey string
 const postCtx postKey = "post"
 
 type CreatePostPayload struct {
-	Title   string   `json:"title" validate:"required,max=100"`
-	Content string   `json:"content" validate:"required,max=1000"`
-	Tags    []string `json:"tags"`
+	Title          string             `json:"title" validate:"required,max=100"`
+	Content        string             `json:"content" validate:"required,max=1000"`
+	Tags           []string           `json:"tags" validate:"max=10,dive,required,max=100"`
+	Visibility     string             `json:"visibility" validate:"omitempty,oneof=public followers unlisted private"`
+	ContentWarning string             `json:"content_warning" validate:"max=200"`
+	Poll           *CreatePollPayload `json:"poll" validate:"omitempty"`
+	ExpiresAt      *time.Time         `json:"expires_at"`
 }
 
 // CreatePost godoc
@@ -46,16 +56,51 @@ func (app *application) createPostHandler(w http.ResponseWriter, r *http.Request
 		app.badRequestError(w, r, err)
 		return
 	}
+	if payloads.ExpiresAt != nil && !payloads.ExpiresAt.After(time.Now()) {
+		app.badRequestError(w, r, errExpiryInPast)
+		return
+	}
 	user := getUserFromContext(r)
 
+	if payloads.Visibility == "" {
+		settings, err := app.getSettings(r.Context(), user.ID)
+		if err != nil {
+			app.internalServerError(w, r, err)
+			return
+		}
+		payloads.Visibility = settings.DefaultVisibility
+	}
+
+	contentHTML, err := markdown.Render(payloads.Content)
+	if err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
 	post := &store.Post{
-		Title:   payloads.Title,
-		Content: payloads.Content,
-		Tags:    payloads.Tags,
-		UserID:  user.ID,
+		Title:          payloads.Title,
+		Content:        payloads.Content,
+		ContentHTML:    contentHTML,
+		Tags:           mergeTags(payloads.Tags, markdown.Hashtags(payloads.Content)),
+		ExplicitTags:   payloads.Tags,
+		UserID:         user.ID,
+		Mentions:       markdown.Mentions(payloads.Content),
+		Links:          markdown.Links(payloads.Content),
+		Visibility:     payloads.Visibility,
+		ContentWarning: payloads.ContentWarning,
+		ExpiresAt:      payloads.ExpiresAt,
 		// UserID:  1,
 	}
 
+	if payloads.Poll != nil {
+		poll, err := payloads.Poll.toPoll()
+		if err != nil {
+			app.badRequestError(w, r, err)
+			return
+		}
+		post.Poll = poll
+	}
+
 	ctx := r.Context()
 
 	if err := app.store.Posts.Create(ctx, post); err != nil {
@@ -63,6 +108,7 @@ func (app *application) createPostHandler(w http.ResponseWriter, r *http.Request
 		return
 	}
 
+	w.Header().Set("ETag", postETag(post))
 	if err := app.jsonResponse(w, http.StatusCreated, post); err != nil {
 		app.internalServerError(w, r, err)
 	}
@@ -75,16 +121,18 @@ func (app *application) createPostHandler(w http.ResponseWriter, r *http.Request
 //	@Tags			posts
 //	@Accept			json
 //	@Produce		json
-//	@Param			id	path		int	true	"Post ID"
-//	@Success		200	{object}	store.Post
-//	@Failure		404	{object}	error
-//	@Failure		500	{object}	error
+//	@Param			id		path		int		true	"Post ID"
+//	@Param			expand	query		bool	false	"Expand a post collapsed by a content warning"
+//	@Success		200		{object}	store.Post
+//	@Failure		404		{object}	error
+//	@Failure		500		{object}	error
 //	@Security		ApiKeyAuth
 //	@Router			/posts/{id} [get]
 func (app *application) getPostHandler(w http.ResponseWriter, r *http.Request) {
 	post := getPostFromCtx(r)
+	user := getUserFromContext(r)
 
-	comments, err := app.store.Comments.GetByPostID(r.Context(), post.ID)
+	comments, err := app.store.Comments.GetByPostID(r.Context(), post.ID, user.ID)
 	if err != nil {
 		app.internalServerError(w, r, err)
 		return
@@ -92,7 +140,40 @@ func (app *application) getPostHandler(w http.ResponseWriter, r *http.Request) {
 
 	post.Comments = comments
 
-	if err := app.jsonResponse(w, http.StatusCreated, post); err != nil {
+	poll, err := app.store.Polls.GetByPostID(r.Context(), post.ID, user.ID)
+	if err != nil && !errors.Is(err, store.ErrNotFound) {
+		app.internalServerError(w, r, err)
+		return
+	}
+	post.Poll = poll
+
+	previews, err := app.store.LinkPreviews.GetByPostIDs(r.Context(), []int64{post.ID})
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+	post.LinkPreviews = previews[post.ID]
+
+	nav, err := app.store.Posts.GetNavigation(r.Context(), post, user.ID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+	post.Navigation = nav
+
+	settings, err := app.getSettings(r.Context(), user.ID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+	applySensitivePreference(user.ID, settings, post, r.URL.Query().Get("expand") == "true")
+
+	if post.UserID != user.ID {
+		app.postStats.Record(analytics.View, post.ID, user.ID)
+	}
+
+	w.Header().Set("ETag", postETag(post))
+	if err := app.jsonResponse(w, http.StatusOK, post); err != nil {
 		app.internalServerError(w, r, err)
 		return
 	}
@@ -136,8 +217,11 @@ func (app *application) deletePostHandler(w http.ResponseWriter, r *http.Request
 }
 
 type UpdatePostPayload struct {
-	Title   *string `json:"title" validate:"omitempty,max=100"`
-	Content *string `json:"content" validate:"omitempty,max=1000"`
+	Title          *string   `json:"title" validate:"omitempty,max=100"`
+	Content        *string   `json:"content" validate:"omitempty,max=1000"`
+	Visibility     *string   `json:"visibility" validate:"omitempty,oneof=public followers unlisted private"`
+	ContentWarning *string   `json:"content_warning" validate:"omitempty,max=200"`
+	Tags           *[]string `json:"tags" validate:"omitempty,max=10,dive,required,max=100"`
 }
 
 // UpdatePost godoc
@@ -147,18 +231,32 @@ type UpdatePostPayload struct {
 //	@Tags			posts
 //	@Accept			json
 //	@Produce		json
-//	@Param			id		path		int					true	"Post ID"
-//	@Param			payload	body		UpdatePostPayload	true	"Post payload"
-//	@Success		200		{object}	store.Post
-//	@Failure		400		{object}	error
-//	@Failure		401		{object}	error
-//	@Failure		404		{object}	error
-//	@Failure		500		{object}	error
+//	@Param			id			path		int					true	"Post ID"
+//	@Param			If-Match	header		string				true	"ETag of the post being edited"
+//	@Param			payload		body		UpdatePostPayload	true	"Post payload"
+//	@Success		200			{object}	store.Post
+//	@Failure		400			{object}	error
+//	@Failure		401			{object}	error
+//	@Failure		404			{object}	error
+//	@Failure		412			{object}	store.Post
+//	@Failure		428			{object}	error
+//	@Failure		500			{object}	error
 //	@Security		ApiKeyAuth
 //	@Router			/posts/{id} [patch]
 func (app *application) updatePostHandler(w http.ResponseWriter, r *http.Request) {
 	post := getPostFromCtx(r)
 
+	ifMatch := r.Header.Get("If-Match")
+	if ifMatch == "" {
+		app.preconditionRequiredError(w, r, errors.New("If-Match header is required"))
+		return
+	}
+	if !etagMatches(ifMatch, postETag(post)) {
+		w.Header().Set("ETag", postETag(post))
+		app.preconditionFailedError(w, r, store.ErrEditConflict, post)
+		return
+	}
+
 	var payload UpdatePostPayload
 
 	if err := readJSON(w, r, &payload); err != nil {
@@ -172,18 +270,54 @@ func (app *application) updatePostHandler(w http.ResponseWriter, r *http.Request
 	}
 
 	if payload.Content != nil {
+		contentHTML, err := markdown.Render(*payload.Content)
+		if err != nil {
+			app.badRequestError(w, r, err)
+			return
+		}
 		post.Content = *payload.Content
+		post.ContentHTML = contentHTML
 	}
 	if payload.Title != nil {
 		post.Title = *payload.Title
 	}
+	if payload.Visibility != nil {
+		post.Visibility = *payload.Visibility
+	}
+	if payload.ContentWarning != nil {
+		post.ContentWarning = *payload.ContentWarning
+	}
+	if payload.Tags != nil {
+		post.ExplicitTags = *payload.Tags
+	}
+	// Hashtags are derived afresh, so ones removed from the content go.
+	post.Tags = mergeTags(post.ExplicitTags, markdown.Hashtags(post.Content))
+	post.Mentions = markdown.Mentions(post.Content)
+	post.Links = markdown.Links(post.Content)
 
-	if err := app.store.Posts.Update(r.Context(), post); err != nil {
-		app.internalServerError(w, r, err)
-		log.Printf("Problem 1")
+	ctx := r.Context()
+
+	if err := app.store.Posts.Update(ctx, post); err != nil {
+		switch {
+		case errors.Is(err, store.ErrEditConflict):
+			// Another edit landed after the post was loaded.
+			current, getErr := app.store.Posts.GetByID(ctx, post.ID)
+			switch {
+			case errors.Is(getErr, store.ErrNotFound):
+				app.notFoundError(w, r, getErr)
+			case getErr != nil:
+				app.internalServerError(w, r, getErr)
+			default:
+				w.Header().Set("ETag", postETag(current))
+				app.preconditionFailedError(w, r, err, current)
+			}
+		default:
+			app.internalServerError(w, r, err)
+		}
 		return
 	}
 
+	w.Header().Set("ETag", postETag(post))
 	if err := app.jsonResponse(w, http.StatusOK, post); err != nil {
 		app.internalServerError(w, r, err)
 		log.Printf("Problem 2")
@@ -191,6 +325,88 @@ func (app *application) updatePostHandler(w http.ResponseWriter, r *http.Request
 	}
 
 }
+
+// postETag derives a strong entity tag from the post version, which is bumped
+// on every update.
+func postETag(post *store.Post) string {
+	return fmt.Sprintf(`"%d-%d"`, post.ID, post.Version)
+}
+
+// etagMatches reports whether an If-Match header lists etag. Weak tags never
+// match since If-Match uses strong comparison.
+func etagMatches(header, etag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || candidate == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// mergeTags appends the content hashtags that are not already present in
+// the explicit tags.
+func mergeTags(tags []string, hashtags []string) []string {
+	merged := append([]string{}, tags...)
+	for _, h := range hashtags {
+		if !slices.Contains(merged, h) {
+			merged = append(merged, h)
+		}
+	}
+	return merged
+}
+
+type PreviewPostPayload struct {
+	Content string `json:"content" validate:"required,max=1000"`
+}
+
+type PostPreview struct {
+	Content     string `json:"content"`
+	ContentHTML string `json:"content_html"`
+}
+
+// PreviewPost godoc
+//
+//	@Summary		Previews post content
+//	@Description	Renders markdown content to sanitised HTML without saving it
+//	@Tags			posts
+//	@Accept			json
+//	@Produce		json
+//	@Param			payload	body		PreviewPostPayload	true	"Preview payload"
+//	@Success		200		{object}	PostPreview
+//	@Failure		400		{object}	error
+//	@Failure		401		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/posts/preview [post]
+func (app *application) previewPostHandler(w http.ResponseWriter, r *http.Request) {
+	var payload PreviewPostPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	contentHTML, err := markdown.Render(payload.Content)
+	if err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	preview := PostPreview{
+		Content:     payload.Content,
+		ContentHTML: contentHTML,
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, preview); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
 func (app *application) postsContextMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		idParam := chi.URLParam(r, "postID")
@@ -213,11 +429,50 @@ func (app *application) postsContextMiddleware(next http.Handler) http.Handler {
 			return
 		}
 
+		allowed, err := app.canViewPost(ctx, getUserFromContext(r), post)
+		if err != nil {
+			app.internalServerError(w, r, err)
+			return
+		}
+		if !allowed {
+			app.notFoundError(w, r, store.ErrNotFound)
+			return
+		}
+
 		ctx = context.WithValue(ctx, postCtx, post)
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
 
+// canViewPost applies the post's visibility and any block between the author
+// and the viewer. Moderators keep access to every post so they can review it.
+func (app *application) canViewPost(ctx context.Context, user *store.User, post *store.Post) (bool, error) {
+	if post.UserID != user.ID {
+		blocked, err := app.store.Blocks.IsBlocked(ctx, user.ID, post.UserID)
+		if err != nil {
+			return false, err
+		}
+		if blocked {
+			return app.checkRolePrecedence(ctx, user, "moderator")
+		}
+	}
+
+	isFollower := false
+	if post.Visibility == store.VisibilityFollowers && post.UserID != user.ID {
+		var err error
+		isFollower, err = app.store.Followers.IsFollowing(ctx, user.ID, post.UserID)
+		if err != nil {
+			return false, err
+		}
+	}
+
+	if post.VisibleTo(user.ID, isFollower) {
+		return true, nil
+	}
+
+	return app.checkRolePrecedence(ctx, user, "moderator")
+}
+
 func getPostFromCtx(r *http.Request) *store.Post {
 	post, _ := r.Context().Value(postCtx).(*store.Post)
 	return post
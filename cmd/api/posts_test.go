@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Aiyanu/gophersocial/internal/store"
+	"go.uber.org/zap"
+)
+
+// fakeGraph answers the follow, block and role lookups of canViewPost.
+type fakeGraph struct {
+	following map[[2]int64]bool
+	blocked   map[[2]int64]bool
+}
+
+func (g *fakeGraph) IsFollowing(_ context.Context, followerID, userID int64) (bool, error) {
+	return g.following[[2]int64{followerID, userID}], nil
+}
+
+func (g *fakeGraph) IsBlocked(_ context.Context, userID, otherID int64) (bool, error) {
+	return g.blocked[[2]int64{userID, otherID}] || g.blocked[[2]int64{otherID, userID}], nil
+}
+
+func (g *fakeGraph) GetByName(_ context.Context, name string) (*store.Role, error) {
+	levels := map[string]int{"user": 1, "moderator": 2, "admin": 3}
+	return &store.Role{Name: name, Level: levels[name]}, nil
+}
+
+func (g *fakeGraph) Follow(context.Context, int64, int64) error   { return nil }
+func (g *fakeGraph) UnFollow(context.Context, int64, int64) error { return nil }
+func (g *fakeGraph) GetFollowers(context.Context, int64, int64, string, int) ([]store.FollowListEntry, string, error) {
+	return nil, "", nil
+}
+func (g *fakeGraph) GetFollowing(context.Context, int64, int64, string, int) ([]store.FollowListEntry, string, error) {
+	return nil, "", nil
+}
+func (g *fakeGraph) Block(context.Context, int64, int64) error   { return nil }
+func (g *fakeGraph) Unblock(context.Context, int64, int64) error { return nil }
+func (g *fakeGraph) GetBlocked(context.Context, int64, string, int) ([]store.BlockListEntry, string, error) {
+	return nil, "", nil
+}
+
+func TestCanViewPost(t *testing.T) {
+	const authorID, followerID, strangerID, blockedID, moderatorID = 1, 2, 3, 4, 5
+
+	graph := &fakeGraph{
+		following: map[[2]int64]bool{{followerID, authorID}: true},
+		blocked:   map[[2]int64]bool{{authorID, blockedID}: true},
+	}
+	app := &application{
+		store:  store.Storage{Followers: graph, Blocks: graph, Roles: graph},
+		logger: zap.NewNop().Sugar(),
+	}
+
+	user := func(id int64, role int) *store.User {
+		return &store.User{ID: id, Role: store.Role{Level: role}}
+	}
+
+	// Every route is authenticated, so an anonymous viewer is one that
+	// matches no user.
+	tests := []struct {
+		viewer string
+		user   *store.User
+		want   map[string]bool
+	}{
+		{"author", user(authorID, 1), map[string]bool{
+			store.VisibilityPublic: true, store.VisibilityFollowers: true, store.VisibilityUnlisted: true, store.VisibilityPrivate: true,
+		}},
+		{"follower", user(followerID, 1), map[string]bool{
+			store.VisibilityPublic: true, store.VisibilityFollowers: true, store.VisibilityUnlisted: true,
+		}},
+		{"non-follower", user(strangerID, 1), map[string]bool{
+			store.VisibilityPublic: true, store.VisibilityUnlisted: true,
+		}},
+		{"anonymous", user(0, 0), map[string]bool{
+			store.VisibilityPublic: true, store.VisibilityUnlisted: true,
+		}},
+		{"blocked", user(blockedID, 1), map[string]bool{}},
+		{"moderator", user(moderatorID, 2), map[string]bool{
+			store.VisibilityPublic: true, store.VisibilityFollowers: true, store.VisibilityUnlisted: true, store.VisibilityPrivate: true,
+		}},
+	}
+
+	visibilities := []string{store.VisibilityPublic, store.VisibilityFollowers, store.VisibilityUnlisted, store.VisibilityPrivate}
+	for _, tt := range tests {
+		for _, visibility := range visibilities {
+			post := &store.Post{UserID: authorID, Visibility: visibility}
+
+			got, err := app.canViewPost(context.Background(), tt.user, post)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want[visibility] {
+				t.Errorf("%s viewing %s post: canViewPost = %v, want %v", tt.viewer, visibility, got, tt.want[visibility])
+			}
+		}
+	}
+}
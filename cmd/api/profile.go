@@ -0,0 +1,195 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Aiyanu/gophersocial/internal/store"
+	"github.com/go-chi/chi/v5"
+)
+
+const (
+	maxAvatarBytes     = 2 << 20
+	maxAvatarDimension = 2048
+)
+
+type UpdateProfilePayload struct {
+	DisplayName *string   `json:"display_name" validate:"omitempty,max=50"`
+	Bio         *string   `json:"bio" validate:"omitempty,max=300"`
+	Location    *string   `json:"location" validate:"omitempty,max=100"`
+	Links       *[]string `json:"links" validate:"omitempty,max=5,dive,http_url,max=200"`
+}
+
+// UpdateProfile godoc
+//
+//	@Summary		Updates the user's profile
+//	@Description	Updates the display name, bio, location and website links of the authenticated user. Omitted fields are left unchanged.
+//	@Tags			users
+//	@Accept			json
+//	@Produce		json
+//	@Param			payload	body		UpdateProfilePayload	true	"Profile payload"
+//	@Success		200		{object}	store.User
+//	@Failure		400		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/users/me [patch]
+func (app *application) updateProfileHandler(w http.ResponseWriter, r *http.Request) {
+	user := getUserFromContext(r)
+
+	var payload UpdateProfilePayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	if payload.DisplayName != nil {
+		user.DisplayName = strings.TrimSpace(*payload.DisplayName)
+	}
+	if payload.Bio != nil {
+		user.Bio = strings.TrimSpace(*payload.Bio)
+	}
+	if payload.Location != nil {
+		user.Location = strings.TrimSpace(*payload.Location)
+	}
+	if payload.Links != nil {
+		user.Links = *payload.Links
+	}
+
+	ctx := r.Context()
+
+	if err := app.store.Users.UpdateProfile(ctx, user); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.cacheStorage.Users.Delete(ctx, user.ID); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, user); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// UploadAvatar godoc
+//
+//	@Summary		Uploads the user's avatar
+//	@Description	Replaces the avatar of the authenticated user with a PNG, JPEG or GIF image of at most 2MB and 2048x2048 pixels
+//	@Tags			users
+//	@Accept			multipart/form-data
+//	@Produce		json
+//	@Param			avatar	formData	file	true	"Avatar image"
+//	@Success		200		{object}	store.User
+//	@Failure		400		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/users/me/avatar [put]
+func (app *application) uploadAvatarHandler(w http.ResponseWriter, r *http.Request) {
+	user := getUserFromContext(r)
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxAvatarBytes+1<<10)
+	if err := r.ParseMultipartForm(maxAvatarBytes); err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	file, _, err := r.FormFile("avatar")
+	if err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(io.LimitReader(file, maxAvatarBytes+1))
+	if err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+	if len(data) > maxAvatarBytes {
+		app.badRequestError(w, r, errors.New("avatar must be at most 2MB"))
+		return
+	}
+
+	cfg, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		app.badRequestError(w, r, errors.New("avatar must be a PNG, JPEG or GIF image"))
+		return
+	}
+	if cfg.Width > maxAvatarDimension || cfg.Height > maxAvatarDimension {
+		app.badRequestError(w, r, fmt.Errorf("avatar must be at most %dx%d pixels", maxAvatarDimension, maxAvatarDimension))
+		return
+	}
+
+	ctx := r.Context()
+
+	// The version parameter makes clients and proxies drop a cached copy of
+	// the previous avatar.
+	avatarURL := fmt.Sprintf("/v1/users/%d/avatar?v=%d", user.ID, time.Now().Unix())
+	if err := app.store.Users.SetAvatar(ctx, user.ID, "image/"+format, data, avatarURL); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+	user.AvatarURL = avatarURL
+
+	if err := app.cacheStorage.Users.Delete(ctx, user.ID); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, user); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// GetAvatar godoc
+//
+//	@Summary		Fetches a user's avatar
+//	@Description	Serves the avatar image uploaded by a user
+//	@Tags			users
+//	@Produce		image/png,image/jpeg,image/gif
+//	@Param			userID	path		int	true	"User ID"
+//	@Success		200		{file}		file
+//	@Failure		404		{object}	error
+//	@Failure		500		{object}	error
+//	@Router			/users/{userID}/avatar [get]
+func (app *application) getAvatarHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := strconv.ParseInt(chi.URLParam(r, "userID"), 10, 64)
+	if err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	contentType, data, err := app.store.Users.GetAvatar(r.Context(), userID)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notFoundError(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+	w.Header().Set("Cache-Control", "public, max-age=86400")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(http.StatusOK)
+	w.Write(data)
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+)
+
+type SearchPostsQuery struct {
+	Q      string `validate:"required,max=200"`
+	Limit  int    `validate:"gte=1,lte=50"`
+	Offset int    `validate:"gte=0,lte=1000"`
+}
+
+// SearchPosts godoc
+//
+//	@Summary		Searches posts
+//	@Description	Full-text search over post titles and content. Supports "quoted phrases", OR and -exclusions; results are ranked by relevance and recency with highlighted snippets.
+//	@Tags			posts
+//	@Produce		json
+//	@Param			q		query		string	true	"Search query"
+//	@Param			limit	query		int		false	"Limit"
+//	@Param			offset	query		int		false	"Offset"
+//	@Success		200		{object}	[]store.PostSearchResult
+//	@Failure		400		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/search/posts [get]
+func (app *application) searchPostsHandler(w http.ResponseWriter, r *http.Request) {
+	qs := r.URL.Query()
+	sq := SearchPostsQuery{Q: qs.Get("q"), Limit: 20}
+
+	if l := qs.Get("limit"); l != "" {
+		limit, err := strconv.Atoi(l)
+		if err != nil {
+			app.badRequestError(w, r, err)
+			return
+		}
+		sq.Limit = limit
+	}
+	if o := qs.Get("offset"); o != "" {
+		offset, err := strconv.Atoi(o)
+		if err != nil {
+			app.badRequestError(w, r, err)
+			return
+		}
+		sq.Offset = offset
+	}
+
+	if err := Validate.Struct(sq); err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	viewer := getUserFromContext(r)
+
+	results, err := app.store.Posts.Search(r.Context(), sq.Q, viewer.ID, sq.Limit, sq.Offset)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	settings, err := app.getSettings(r.Context(), viewer.ID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	for i := range results {
+		applySensitivePreference(viewer.ID, settings, &results[i].Post, false)
+		if results[i].Collapsed {
+			results[i].Snippet = ""
+		}
+	}
+	if err := app.jsonResponse(w, http.StatusOK, results); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/Aiyanu/gophersocial/internal/store"
+)
+
+// applySensitivePreference collapses a post with a content warning or
+// sensitive media unless the viewer wrote it, opted to auto-expand such
+// posts or explicitly asked to expand it.
+func applySensitivePreference(viewerID int64, settings *store.UserSettings, post *store.Post, expand bool) {
+	if !post.IsSensitive() || post.UserID == viewerID || expand {
+		return
+	}
+
+	if settings.SensitiveContent != store.SensitiveExpand {
+		post.Collapse()
+	}
+}
+
+type MarkSensitivePayload struct {
+	Sensitive bool `json:"sensitive"`
+}
+
+// MarkPostSensitive godoc
+//
+//	@Summary		Marks a post as sensitive
+//	@Description	Flags or unflags a post as sensitive media
+//	@Tags			posts
+//	@Accept			json
+//	@Produce		json
+//	@Param			id		path		int						true	"Post ID"
+//	@Param			payload	body		MarkSensitivePayload	true	"Sensitive flag"
+//	@Success		204		{string}	string					"Post updated"
+//	@Failure		400		{object}	error
+//	@Failure		403		{object}	error
+//	@Failure		404		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/posts/{id}/sensitive [put]
+func (app *application) markPostSensitiveHandler(w http.ResponseWriter, r *http.Request) {
+	post := getPostFromCtx(r)
+
+	var payload MarkSensitivePayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	if err := app.store.Posts.SetSensitive(r.Context(), post.ID, payload.Sensitive); err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notFoundError(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+type UpdatePreferencesPayload struct {
+	SensitiveContent string `json:"sensitive_content" validate:"required,oneof=expand collapse hide"`
+}
+
+// UpdatePreferences godoc
+//
+//	@Summary		Updates the user's preferences
+//	@Description	Sets whether posts with content warnings or sensitive media are expanded, collapsed or hidden. Superseded by PATCH /users/me/settings.
+//	@Tags			users
+//	@Accept			json
+//	@Produce		json
+//	@Param			payload	body		UpdatePreferencesPayload	true	"Preferences"
+//	@Success		200		{object}	store.UserSettings
+//	@Failure		400		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Deprecated
+//	@Router			/users/me/preferences [patch]
+func (app *application) updatePreferencesHandler(w http.ResponseWriter, r *http.Request) {
+	var payload UpdatePreferencesPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	app.updateSettings(w, r, "", func(s *store.Settings) error {
+		s.SensitiveContent = payload.SensitiveContent
+		return nil
+	})
+}
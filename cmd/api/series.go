@@ -0,0 +1,202 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/Aiyanu/gophersocial/internal/store"
+	"github.com/go-chi/chi/v5"
+)
+
+type CreateSeriesPayload struct {
+	Title       string `json:"title" validate:"required,max=100"`
+	Description string `json:"description" validate:"max=500"`
+}
+
+type AddToSeriesPayload struct {
+	SeriesID int64 `json:"series_id" validate:"required,gt=0"`
+}
+
+// CreateSeries godoc
+//
+//	@Summary		Creates a series
+//	@Description	Creates an empty named series that the author's posts can be added to
+//	@Tags			series
+//	@Accept			json
+//	@Produce		json
+//	@Param			payload	body		CreateSeriesPayload	true	"Series payload"
+//	@Success		201		{object}	store.Series
+//	@Failure		400		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/series [post]
+func (app *application) createSeriesHandler(w http.ResponseWriter, r *http.Request) {
+	var payload CreateSeriesPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	user := getUserFromContext(r)
+
+	series := &store.Series{
+		UserID:      user.ID,
+		Title:       payload.Title,
+		Description: payload.Description,
+	}
+	if err := app.store.Series.Create(r.Context(), series); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusCreated, series); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// GetSeries godoc
+//
+//	@Summary		Fetches a series
+//	@Description	Fetches a series with a table of contents of the posts the user may see
+//	@Tags			series
+//	@Produce		json
+//	@Param			seriesID	path		int	true	"Series ID"
+//	@Success		200			{object}	store.Series
+//	@Failure		400			{object}	error
+//	@Failure		404			{object}	error
+//	@Failure		500			{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/series/{seriesID} [get]
+func (app *application) getSeriesHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "seriesID"), 10, 64)
+	if err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	viewer := getUserFromContext(r)
+
+	series, err := app.store.Series.GetByID(r.Context(), id, viewer.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notFoundError(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, series); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// AddPostToSeries godoc
+//
+//	@Summary		Adds a post to a series
+//	@Description	Appends one of the user's posts to the end of one of their series
+//	@Tags			series
+//	@Accept			json
+//	@Produce		json
+//	@Param			id		path		int					true	"Post ID"
+//	@Param			payload	body		AddToSeriesPayload	true	"Series payload"
+//	@Success		204		{string}	string				"Post added"
+//	@Failure		400		{object}	error
+//	@Failure		403		{object}	error
+//	@Failure		404		{object}	error
+//	@Failure		409		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/posts/{id}/series [put]
+func (app *application) addPostToSeriesHandler(w http.ResponseWriter, r *http.Request) {
+	post := getPostFromCtx(r)
+	user := getUserFromContext(r)
+
+	var payload AddToSeriesPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	ctx := r.Context()
+
+	series, err := app.store.Series.GetByID(ctx, payload.SeriesID, user.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notFoundError(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	if post.UserID != user.ID || series.UserID != user.ID {
+		app.forbiddenError(w, r, errors.New("only the author can add a post to their series"))
+		return
+	}
+
+	if err := app.store.Series.AddPost(ctx, series.ID, post.ID); err != nil {
+		switch {
+		case errors.Is(err, store.ErrConflict):
+			app.conflictError(w, r, errors.New("post is already part of a series"))
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// RemovePostFromSeries godoc
+//
+//	@Summary		Removes a post from its series
+//	@Description	Takes one of the user's posts out of the series it belongs to
+//	@Tags			series
+//	@Produce		json
+//	@Param			id	path		int		true	"Post ID"
+//	@Success		204	{string}	string	"Post removed"
+//	@Failure		403	{object}	error
+//	@Failure		404	{object}	error
+//	@Failure		500	{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/posts/{id}/series [delete]
+func (app *application) removePostFromSeriesHandler(w http.ResponseWriter, r *http.Request) {
+	post := getPostFromCtx(r)
+	user := getUserFromContext(r)
+
+	if post.UserID != user.ID {
+		app.forbiddenError(w, r, errors.New("only the author can remove a post from their series"))
+		return
+	}
+
+	if post.SeriesID == nil {
+		app.notFoundError(w, r, store.ErrNotFound)
+		return
+	}
+
+	if err := app.store.Series.RemovePost(r.Context(), *post.SeriesID, post.ID); err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notFoundError(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
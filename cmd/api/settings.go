@@ -0,0 +1,137 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/Aiyanu/gophersocial/internal/store"
+)
+
+// GetSettings godoc
+//
+//	@Summary		Fetches the user's settings
+//	@Description	Fetches the settings document of the authenticated user, with defaults for anything never changed
+//	@Tags			users
+//	@Produce		json
+//	@Success		200	{object}	store.UserSettings
+//	@Failure		500	{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/users/me/settings [get]
+func (app *application) getSettingsHandler(w http.ResponseWriter, r *http.Request) {
+	user := getUserFromContext(r)
+
+	settings, err := app.getSettings(r.Context(), user.ID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	w.Header().Set("ETag", settingsETag(user.ID, settings))
+	if err := app.jsonResponse(w, http.StatusOK, settings); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// UpdateSettings godoc
+//
+//	@Summary		Updates the user's settings
+//	@Description	Applies a partial settings document: only the fields present in the payload change. With If-Match, the update is refused if the settings changed since they were read.
+//	@Tags			users
+//	@Accept			json
+//	@Produce		json
+//	@Param			If-Match	header		string			false	"ETag of the settings being edited"
+//	@Param			payload		body		store.Settings	true	"Partial settings"
+//	@Success		200			{object}	store.UserSettings
+//	@Failure		400			{object}	error
+//	@Failure		412			{object}	store.UserSettings
+//	@Failure		500			{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/users/me/settings [patch]
+func (app *application) updateSettingsHandler(w http.ResponseWriter, r *http.Request) {
+	app.updateSettings(w, r, r.Header.Get("If-Match"), func(s *store.Settings) error {
+		return readJSON(w, r, s)
+	})
+}
+
+// updateSettings loads the user's current settings, lets apply change them,
+// validates the result and saves it. A non-empty ifMatch must match the
+// current settings.
+func (app *application) updateSettings(w http.ResponseWriter, r *http.Request, ifMatch string, apply func(*store.Settings) error) {
+	user := getUserFromContext(r)
+	ctx := r.Context()
+
+	settings, err := app.store.Settings.Get(ctx, user.ID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if ifMatch != "" && !etagMatches(ifMatch, settingsETag(user.ID, settings)) {
+		w.Header().Set("ETag", settingsETag(user.ID, settings))
+		app.preconditionFailedError(w, r, store.ErrEditConflict, settings)
+		return
+	}
+
+	if err := apply(&settings.Settings); err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(settings.Settings); err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	if err := app.store.Settings.Update(ctx, user.ID, settings); err != nil {
+		switch {
+		case errors.Is(err, store.ErrEditConflict):
+			// Another update landed after the settings were loaded.
+			current, getErr := app.store.Settings.Get(ctx, user.ID)
+			if getErr != nil {
+				app.internalServerError(w, r, getErr)
+				return
+			}
+			w.Header().Set("ETag", settingsETag(user.ID, current))
+			app.preconditionFailedError(w, r, err, current)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	if err := app.cacheStorage.Settings.Set(ctx, user.ID, settings); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	w.Header().Set("ETag", settingsETag(user.ID, settings))
+	if err := app.jsonResponse(w, http.StatusOK, settings); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// getSettings reads a user's settings through the cache.
+func (app *application) getSettings(ctx context.Context, userID int64) (*store.UserSettings, error) {
+	settings, err := app.cacheStorage.Settings.Get(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if settings == nil {
+		settings, err = app.store.Settings.Get(ctx, userID)
+		if err != nil {
+			return nil, err
+		}
+		if err := app.cacheStorage.Settings.Set(ctx, userID, settings); err != nil {
+			return nil, err
+		}
+	}
+	return settings, nil
+}
+
+// settingsETag derives a strong entity tag from the settings version, which
+// is bumped on every update.
+func settingsETag(userID int64, settings *store.UserSettings) string {
+	return fmt.Sprintf(`"settings-%d-%d"`, userID, settings.Version)
+}
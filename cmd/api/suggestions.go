@@ -0,0 +1,76 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/Aiyanu/gophersocial/internal/store"
+)
+
+// GetSuggestions godoc
+//
+//	@Summary		Fetches accounts to follow
+//	@Description	Suggests accounts followed by the people the user follows, posting under the same tags, or popular. Accounts the user follows, blocks, mutes or dismissed are never suggested.
+//	@Tags			users
+//	@Accept			json
+//	@Produce		json
+//	@Param			limit	query		int	false	"Number of suggestions (max 50)"
+//	@Success		200		{array}		store.Suggestion
+//	@Failure		400		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/users/me/suggestions [get]
+func (app *application) getSuggestionsHandler(w http.ResponseWriter, r *http.Request) {
+	user := getUserFromContext(r)
+
+	limit, err := parseLimit(r, 10, 50)
+	if err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	suggestions, err := app.store.Suggestions.Get(r.Context(), user.ID, limit)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, suggestions); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// DismissSuggestion godoc
+//
+//	@Summary		Dismisses a suggested account
+//	@Description	Stops suggesting the user to the authenticated user
+//	@Tags			users
+//	@Accept			json
+//	@Produce		json
+//	@Param			userID	path		int		true	"User ID"
+//	@Success		204		{string}	string	"Suggestion dismissed"
+//	@Failure		400		{object}	error
+//	@Failure		404		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/users/me/suggestions/{userID} [delete]
+func (app *application) dismissSuggestionHandler(w http.ResponseWriter, r *http.Request) {
+	user := getUserFromContext(r)
+
+	userID, ok := app.otherUserID(w, r, user)
+	if !ok {
+		return
+	}
+
+	if err := app.store.Suggestions.Dismiss(r.Context(), user.ID, userID); err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notFoundError(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
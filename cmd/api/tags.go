@@ -0,0 +1,272 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/Aiyanu/gophersocial/internal/store"
+	"github.com/go-chi/chi/v5"
+)
+
+// GetTagPosts godoc
+//
+//	@Summary		Fetches posts for a hashtag
+//	@Description	Fetches the posts tagged explicitly or through a #hashtag in their content
+//	@Tags			tags
+//	@Accept			json
+//	@Produce		json
+//	@Param			tag		path		string	true	"Tag"
+//	@Param			limit	query		int		false	"Limit"
+//	@Param			offset	query		int		false	"Offset"
+//	@Param			sort	query		string	false	"Sort"
+//	@Success		200		{object}	[]store.PostWithMetadata
+//	@Failure		400		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/tags/{tag}/posts [get]
+func (app *application) getTagPostsHandler(w http.ResponseWriter, r *http.Request) {
+	tag := chi.URLParam(r, "tag")
+
+	defaultFQ := store.PaginatedFeedQuery{
+		Limit:  20,
+		Offset: 0,
+		Sort:   "desc",
+	}
+
+	fq, err := defaultFQ.Parse(r)
+	if err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(fq); err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	viewer := getUserFromContext(r)
+
+	posts, err := app.store.Posts.GetByHashtag(r.Context(), tag, viewer.ID, fq)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, posts); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// GetTag godoc
+//
+//	@Summary		Fetches a tag
+//	@Description	Fetches a tag by slug or alias with its aliases
+//	@Tags			tags
+//	@Produce		json
+//	@Param			tag	path		string	true	"Tag"
+//	@Success		200	{object}	store.Tag
+//	@Failure		404	{object}	error
+//	@Failure		500	{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/tags/{tag} [get]
+func (app *application) getTagHandler(w http.ResponseWriter, r *http.Request) {
+	tag, err := app.store.Tags.GetBySlug(r.Context(), chi.URLParam(r, "tag"))
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notFoundError(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, tag); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+type RenameTagPayload struct {
+	Name string `json:"name" validate:"required,max=100"`
+}
+
+// RenameTag godoc
+//
+//	@Summary		Renames a tag
+//	@Description	Renames a tag, keeps the old slug as an alias and retags existing posts
+//	@Tags			tags
+//	@Accept			json
+//	@Produce		json
+//	@Param			tag		path		string				true	"Tag"
+//	@Param			payload	body		RenameTagPayload	true	"New name"
+//	@Success		200		{object}	store.Tag
+//	@Failure		400		{object}	error
+//	@Failure		403		{object}	error
+//	@Failure		404		{object}	error
+//	@Failure		409		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/tags/{tag} [patch]
+func (app *application) renameTagHandler(w http.ResponseWriter, r *http.Request) {
+	var payload RenameTagPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	if store.NormalizeTag(payload.Name) == "" {
+		app.badRequestError(w, r, errors.New("tag name is empty"))
+		return
+	}
+
+	tag, err := app.store.Tags.Rename(r.Context(), chi.URLParam(r, "tag"), payload.Name)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notFoundError(w, r, err)
+		case errors.Is(err, store.ErrConflict):
+			app.conflictError(w, r, errors.New("another tag already goes by that name, merge the tags instead"))
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, tag); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+type MergeTagPayload struct {
+	Into string `json:"into" validate:"required,max=100"`
+}
+
+// MergeTag godoc
+//
+//	@Summary		Merges a tag into another
+//	@Description	Makes a tag an alias of another one and retags existing posts
+//	@Tags			tags
+//	@Accept			json
+//	@Produce		json
+//	@Param			tag		path		string			true	"Tag to merge"
+//	@Param			payload	body		MergeTagPayload	true	"Target tag"
+//	@Success		200		{object}	store.Tag
+//	@Failure		400		{object}	error
+//	@Failure		403		{object}	error
+//	@Failure		404		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/tags/{tag}/merge [post]
+func (app *application) mergeTagHandler(w http.ResponseWriter, r *http.Request) {
+	var payload MergeTagPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	tag, err := app.store.Tags.Merge(r.Context(), chi.URLParam(r, "tag"), payload.Into)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notFoundError(w, r, err)
+		case errors.Is(err, store.ErrConflict):
+			app.badRequestError(w, r, errors.New("cannot merge a tag into itself"))
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, tag); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// GetTrendingTags godoc
+//
+//	@Summary		Fetches trending tags
+//	@Description	Fetches the tags trending among public posts over a sliding window
+//	@Tags			tags
+//	@Produce		json
+//	@Param			window	query		string	false	"Window: day or week"
+//	@Param			limit	query		int		false	"Limit"
+//	@Success		200		{object}	[]store.TrendingTag
+//	@Failure		400		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/tags/trending [get]
+func (app *application) getTrendingTagsHandler(w http.ResponseWriter, r *http.Request) {
+	window := r.URL.Query().Get("window")
+	if window == "" {
+		window = "day"
+	}
+	if _, ok := store.TrendingWindows[window]; !ok {
+		app.badRequestError(w, r, errors.New("window must be day or week"))
+		return
+	}
+
+	limit, err := parseLimit(r, 20, 100)
+	if err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	tags, err := app.store.Tags.Trending(r.Context(), window, limit)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, tags); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// AutocompleteTags godoc
+//
+//	@Summary		Autocompletes tags
+//	@Description	Suggests tags matching a prefix or similar to the query
+//	@Tags			tags
+//	@Produce		json
+//	@Param			q		query		string	true	"Query"
+//	@Param			limit	query		int		false	"Limit"
+//	@Success		200		{object}	[]store.Tag
+//	@Failure		400		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/tags/autocomplete [get]
+func (app *application) autocompleteTagsHandler(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query().Get("q")
+	if len(q) > 100 {
+		app.badRequestError(w, r, errors.New("query is too long"))
+		return
+	}
+
+	limit, err := parseLimit(r, 10, 20)
+	if err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	viewer := getUserFromContext(r)
+
+	tags, err := app.store.Tags.Autocomplete(r.Context(), q, viewer.ID, limit)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, tags); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/Aiyanu/gophersocial/internal/markdown"
+	"github.com/Aiyanu/gophersocial/internal/store"
+	"github.com/go-chi/chi/v5"
+)
+
+type ThreadPartPayload struct {
+	Title   string   `json:"title" validate:"required,max=100"`
+	Content string   `json:"content" validate:"required,max=1000"`
+	Tags    []string `json:"tags" validate:"max=10,dive,required,max=100"`
+}
+
+type CreateThreadPayload struct {
+	Visibility     string              `json:"visibility" validate:"omitempty,oneof=public followers unlisted private"`
+	ContentWarning string              `json:"content_warning" validate:"max=200"`
+	Parts          []ThreadPartPayload `json:"parts" validate:"required,min=2,max=25,dive"`
+}
+
+// CreateThread godoc
+//
+//	@Summary		Creates a thread
+//	@Description	Creates an ordered chain of posts at once. Every part shares the thread's visibility and content warning.
+//	@Tags			threads
+//	@Accept			json
+//	@Produce		json
+//	@Param			payload	body		CreateThreadPayload	true	"Thread payload"
+//	@Success		201		{object}	store.Thread
+//	@Failure		400		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/threads [post]
+func (app *application) createThreadHandler(w http.ResponseWriter, r *http.Request) {
+	var payload CreateThreadPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	user := getUserFromContext(r)
+
+	if payload.Visibility == "" {
+		settings, err := app.getSettings(r.Context(), user.ID)
+		if err != nil {
+			app.internalServerError(w, r, err)
+			return
+		}
+		payload.Visibility = settings.DefaultVisibility
+	}
+
+	parts := make([]*store.Post, len(payload.Parts))
+	for i, part := range payload.Parts {
+		contentHTML, err := markdown.Render(part.Content)
+		if err != nil {
+			app.badRequestError(w, r, err)
+			return
+		}
+
+		parts[i] = &store.Post{
+			Title:          part.Title,
+			Content:        part.Content,
+			ContentHTML:    contentHTML,
+			Tags:           mergeTags(part.Tags, markdown.Hashtags(part.Content)),
+			ExplicitTags:   part.Tags,
+			Mentions:       markdown.Mentions(part.Content),
+			Links:          markdown.Links(part.Content),
+			Visibility:     payload.Visibility,
+			ContentWarning: payload.ContentWarning,
+		}
+	}
+
+	thread, err := app.store.Threads.Create(r.Context(), user.ID, parts)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusCreated, thread); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// GetThread godoc
+//
+//	@Summary		Fetches a thread
+//	@Description	Fetches the parts of a thread the user may see, in order
+//	@Tags			threads
+//	@Produce		json
+//	@Param			threadID	path		int	true	"Thread ID"
+//	@Success		200			{object}	store.Thread
+//	@Failure		400			{object}	error
+//	@Failure		404			{object}	error
+//	@Failure		500			{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/threads/{threadID} [get]
+func (app *application) getThreadHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "threadID"), 10, 64)
+	if err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	ctx := r.Context()
+	viewer := getUserFromContext(r)
+
+	thread, err := app.store.Threads.GetByID(ctx, id, viewer.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notFoundError(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	if err := app.attachPolls(ctx, thread.Posts, viewer.ID); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+	if err := app.attachLinkPreviews(ctx, thread.Posts); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+	settings, err := app.getSettings(ctx, viewer.ID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+	for i := range thread.Posts {
+		applySensitivePreference(viewer.ID, settings, &thread.Posts[i].Post, false)
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, thread); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
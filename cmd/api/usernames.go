@@ -0,0 +1,132 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"time"
+
+	"github.com/Aiyanu/gophersocial/internal/store"
+	"github.com/go-chi/chi/v5"
+)
+
+// usernameRx keeps new names mentionable as @username.
+var usernameRx = regexp.MustCompile(`^\w{3,100}$`)
+
+type ChangeUsernamePayload struct {
+	Username string `json:"username" validate:"required,max=100"`
+}
+
+// ChangeUsername godoc
+//
+//	@Summary		Changes the user's username
+//	@Description	Renames the authenticated user, at most once per cooldown period. The old name keeps redirecting to the account and stays reserved for a grace period.
+//	@Tags			users
+//	@Accept			json
+//	@Produce		json
+//	@Param			payload	body		ChangeUsernamePayload	true	"Username payload"
+//	@Success		200		{object}	store.User
+//	@Failure		400		{object}	error
+//	@Failure		409		{object}	error
+//	@Failure		429		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/users/me/username [put]
+func (app *application) changeUsernameHandler(w http.ResponseWriter, r *http.Request) {
+	user := getUserFromContext(r)
+
+	var payload ChangeUsernamePayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+	if !usernameRx.MatchString(payload.Username) {
+		app.badRequestError(w, r, errors.New("username must be 3 to 100 letters, digits or underscores"))
+		return
+	}
+
+	ctx := r.Context()
+
+	err := app.store.Users.ChangeUsername(ctx, user.ID, payload.Username, app.config.usernameCooldown, app.config.usernameGrace)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrDuplicateUsername):
+			app.conflictError(w, r, err)
+		case errors.Is(err, store.ErrUsernameCooldown):
+			retryAfter := app.config.usernameCooldown
+			if user.UsernameChangedAt != nil {
+				retryAfter = time.Until(user.UsernameChangedAt.Add(app.config.usernameCooldown))
+			}
+			app.tooManyRequestsError(w, r, err, retryAfter)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	if err := app.cacheStorage.Users.Delete(ctx, user.ID); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	user, err = app.store.Users.GetByID(ctx, user.ID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, user); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// GetUserByUsername godoc
+//
+//	@Summary		Fetches a user by username
+//	@Description	Fetches the user holding a username. A username given up in a recent rename redirects to the account that held it.
+//	@Tags			users
+//	@Produce		json
+//	@Param			username	path		string	true	"Username"
+//	@Success		200			{object}	store.User
+//	@Success		302			{string}	string	"Redirect to the renamed account"
+//	@Failure		404			{object}	error
+//	@Failure		500			{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/users/by-username/{username} [get]
+func (app *application) getUserByUsernameHandler(w http.ResponseWriter, r *http.Request) {
+	username := chi.URLParam(r, "username")
+	ctx := r.Context()
+
+	user, err := app.store.Users.GetByUsername(ctx, username)
+	if err == nil {
+		if err := app.jsonResponse(w, http.StatusOK, user); err != nil {
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+	if !errors.Is(err, store.ErrNotFound) {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	// The name may be reassigned once its reservation ends, so the redirect
+	// is not permanent.
+	id, err := app.store.Users.ResolveFormerUsername(ctx, username)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notFoundError(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	http.Redirect(w, r, fmt.Sprintf("/v1/users/%d", id), http.StatusFound)
+}
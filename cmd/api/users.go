@@ -2,8 +2,10 @@ package main
 
 import (
 	"context"
+	"errors"
 	"net/http"
 	"strconv"
+	"strings"
 
 	"github.com/Aiyanu/gophersocial/internal/store"
 	"github.com/go-chi/chi/v5"
@@ -55,14 +57,17 @@ func (app *application) getUserHandler(w http.ResponseWriter, r *http.Request) {
 // FollowUser godoc
 //
 //	@Summary		Follows a user
-//	@Description	Follows a user by ID
+//	@Description	Follows a user by ID. Following a private account sends a follow request instead, answered with 202.
 //	@Tags			users
 //	@Accept			json
 //	@Produce		json
 //	@Param			userID	path		int		true	"User ID"
 //	@Success		204		{string}	string	"User followed"
+//	@Success		202		{object}	FollowRequestStatus
 //	@Failure		400		{object}	error	"User payload missing"
+//	@Failure		403		{object}	error	"Either user blocks the other"
 //	@Failure		404		{object}	error	"User not found"
+//	@Failure		409		{object}	error	"Already following or requested"
 //	@Security		ApiKeyAuth
 //	@Router			/users/{userID}/follow [put]
 func (app *application) followUserHandler(w http.ResponseWriter, r *http.Request) {
@@ -74,19 +79,58 @@ func (app *application) followUserHandler(w http.ResponseWriter, r *http.Request
 		return
 	}
 
+	if followerID == followerUser.ID {
+		app.badRequestError(w, r, errors.New("cannot follow yourself"))
+		return
+	}
+
 	ctx := r.Context()
 
+	target, err := app.getUser(ctx, followerID)
+	if err != nil {
+		switch err {
+		case store.ErrNotFound:
+			app.notFoundError(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	blocked, err := app.store.Blocks.IsBlocked(ctx, followerUser.ID, target.ID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+	if blocked {
+		app.forbiddenError(w, r, errBlocked)
+		return
+	}
+
+	if target.IsPrivate {
+		app.requestFollow(w, r, followerUser.ID, target.ID)
+		return
+	}
+
 	if err := app.store.Followers.Follow(ctx, followerUser.ID, followerID); err != nil {
 		switch err {
 		case store.ErrConflict:
 			app.conflictError(w, r, err)
 			return
+		case store.ErrNotFound:
+			app.notFoundError(w, r, err)
+			return
 		default:
 			app.internalServerError(w, r, err)
 			return
 		}
 	}
 
+	if err := app.invalidateFollowCounts(ctx, followerUser.ID, followerID); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
 	if err := app.jsonResponse(w, http.StatusNoContent, nil); err != nil {
 		app.internalServerError(w, r, err)
 
@@ -124,6 +168,11 @@ func (app *application) unfollowUserHandler(w http.ResponseWriter, r *http.Reque
 		}
 	}
 
+	if err := app.invalidateFollowCounts(ctx, followerUser.ID, followerID); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
 	// Respond with a no-content status if successful
 	if err := app.jsonResponse(w, http.StatusNoContent, nil); err != nil {
 		app.internalServerError(w, r, err)
@@ -191,3 +240,202 @@ func getUserFromContext(r *http.Request) *store.User {
 	user, _ := r.Context().Value(userCtx).(*store.User)
 	return user
 }
+
+// GetUserMentions godoc
+//
+//	@Summary		Fetches posts mentioning a user
+//	@Description	Fetches the posts whose content mentions the user by @username
+//	@Tags			users
+//	@Accept			json
+//	@Produce		json
+//	@Param			userID	path		int		true	"User ID"
+//	@Param			limit	query		int		false	"Limit"
+//	@Param			offset	query		int		false	"Offset"
+//	@Param			sort	query		string	false	"Sort"
+//	@Success		200		{object}	[]store.PostWithMetadata
+//	@Failure		400		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/users/{userID}/mentions [get]
+func (app *application) getUserMentionsHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := strconv.ParseInt(chi.URLParam(r, "userID"), 10, 64)
+	if err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	defaultFQ := store.PaginatedFeedQuery{
+		Limit:  20,
+		Offset: 0,
+		Sort:   "desc",
+	}
+
+	fq, err := defaultFQ.Parse(r)
+	if err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(fq); err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	viewer := getUserFromContext(r)
+
+	posts, err := app.store.Posts.GetByMention(r.Context(), userID, viewer.ID, fq)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, posts); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+type UserPostsPage struct {
+	Pinned     []store.PostWithMetadata `json:"pinned,omitempty"`
+	Posts      []store.PostWithMetadata `json:"posts"`
+	NextCursor string                   `json:"next_cursor,omitempty"`
+}
+
+// GetUserPosts godoc
+//
+//	@Summary		Fetches a user's posts
+//	@Description	Fetches the posts a user wrote that the viewer may see, newest first. The first unfiltered page also carries the user's pinned posts.
+//	@Tags			users
+//	@Accept			json
+//	@Produce		json
+//	@Param			userID	path		int		true	"User ID"
+//	@Param			limit	query		int		false	"Limit"
+//	@Param			cursor	query		string	false	"Cursor returned by the previous page"
+//	@Param			sort	query		string	false	"Sort"
+//	@Param			tags	query		string	false	"Comma separated tags"
+//	@Param			search	query		string	false	"Search"
+//	@Success		200		{object}	UserPostsPage
+//	@Failure		400		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/users/{userID}/posts [get]
+func (app *application) getUserPostsHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := strconv.ParseInt(chi.URLParam(r, "userID"), 10, 64)
+	if err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	defaultFQ := store.PaginatedFeedQuery{
+		Limit: 20,
+		Sort:  "desc",
+	}
+
+	fq, err := defaultFQ.Parse(r)
+	if err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(fq); err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	ctx := r.Context()
+	viewer := getUserFromContext(r)
+
+	posts, next, err := app.store.Posts.GetByUserID(ctx, userID, viewer.ID, fq)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrInvalidCursor):
+			app.badRequestError(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	page := UserPostsPage{Posts: posts, NextCursor: next}
+	if fq.Cursor == "" && !fq.Filtered() {
+		page.Pinned, err = app.store.Pins.GetByUserID(ctx, userID, viewer.ID)
+		if err != nil {
+			app.internalServerError(w, r, err)
+			return
+		}
+	}
+
+	all := append(append([]store.PostWithMetadata{}, page.Pinned...), page.Posts...)
+	if err := app.attachPolls(ctx, all, viewer.ID); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+	if err := app.attachLinkPreviews(ctx, all); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+	settings, err := app.getSettings(ctx, viewer.ID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+	for i := range all {
+		applySensitivePreference(viewer.ID, settings, &all[i].Post, false)
+	}
+	page.Pinned, page.Posts = all[:len(page.Pinned)], all[len(page.Pinned):]
+
+	if err := app.jsonResponse(w, http.StatusOK, page); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// SearchUsers godoc
+//
+//	@Summary		Searches users
+//	@Description	Finds users by username or display name prefix and similarity, for search and @mention autocomplete. Accounts the user follows, and accounts they follow, rank first.
+//	@Tags			users
+//	@Produce		json
+//	@Param			q		query		string	true	"Query, with or without a leading @"
+//	@Param			limit	query		int		false	"Limit, up to 20"
+//	@Success		200		{object}	[]store.UserSummary
+//	@Failure		400		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/users/search [get]
+func (app *application) searchUsersHandler(w http.ResponseWriter, r *http.Request) {
+	q := strings.ToLower(strings.TrimSpace(r.URL.Query().Get("q")))
+	if q == "" || len(q) > 50 {
+		app.badRequestError(w, r, errors.New("query must be between 1 and 50 characters"))
+		return
+	}
+
+	limit, err := parseLimit(r, 8, 20)
+	if err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	ctx := r.Context()
+	viewer := getUserFromContext(r)
+
+	users, err := app.cacheStorage.UserSearch.Get(ctx, viewer.ID, q, limit)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+	if users == nil {
+		users, err = app.store.Users.Search(ctx, q, viewer.ID, limit)
+		if err != nil {
+			app.internalServerError(w, r, err)
+			return
+		}
+		if err := app.cacheStorage.UserSearch.Set(ctx, viewer.ID, q, limit, users); err != nil {
+			app.internalServerError(w, r, err)
+			return
+		}
+	}
+
+	w.Header().Set("Cache-Control", "private, max-age=30")
+	if err := app.jsonResponse(w, http.StatusOK, users); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
@@ -0,0 +1,4 @@
+ALTER TABLE posts
+ADD COLUMN content_html TEXT NOT NULL DEFAULT '';
+ALTER TABLE comments
+ADD COLUMN content_html TEXT NOT NULL DEFAULT '';
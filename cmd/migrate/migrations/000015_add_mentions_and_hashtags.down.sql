@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS hashtags;
+DROP TABLE IF EXISTS mentions;
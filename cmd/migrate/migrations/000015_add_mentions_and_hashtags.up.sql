@@ -0,0 +1,25 @@
+CREATE TABLE IF NOT EXISTS mentions (
+    id bigserial PRIMARY KEY,
+    post_id bigint NOT NULL REFERENCES posts (id) ON DELETE CASCADE,
+    comment_id bigint REFERENCES comments (id) ON DELETE CASCADE,
+    user_id bigint NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW()
+);
+CREATE INDEX IF NOT EXISTS idx_mentions_user_id ON mentions (user_id);
+CREATE INDEX IF NOT EXISTS idx_mentions_post_id ON mentions (post_id);
+
+CREATE TABLE IF NOT EXISTS hashtags (
+    id bigserial PRIMARY KEY,
+    post_id bigint NOT NULL REFERENCES posts (id) ON DELETE CASCADE,
+    comment_id bigint REFERENCES comments (id) ON DELETE CASCADE,
+    tag varchar(100) NOT NULL,
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW()
+);
+CREATE INDEX IF NOT EXISTS idx_hashtags_tag ON hashtags (tag);
+CREATE INDEX IF NOT EXISTS idx_hashtags_post_id ON hashtags (post_id);
+
+-- Existing post tags become the initial hashtag rows.
+INSERT INTO hashtags (post_id, tag)
+SELECT DISTINCT p.id, t.tag
+FROM posts p, unnest(p.tags) AS t(tag)
+WHERE t.tag IS NOT NULL;
@@ -0,0 +1,24 @@
+CREATE TABLE IF NOT EXISTS polls (
+    id bigserial PRIMARY KEY,
+    post_id bigint NOT NULL UNIQUE REFERENCES posts (id) ON DELETE CASCADE,
+    multiple_choice boolean NOT NULL DEFAULT FALSE,
+    hide_results boolean NOT NULL DEFAULT FALSE,
+    closes_at timestamp(0) with time zone NOT NULL,
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS poll_options (
+    id bigserial PRIMARY KEY,
+    poll_id bigint NOT NULL REFERENCES polls (id) ON DELETE CASCADE,
+    position int NOT NULL,
+    text varchar(100) NOT NULL,
+    UNIQUE (poll_id, position)
+);
+
+CREATE TABLE IF NOT EXISTS poll_votes (
+    poll_id bigint NOT NULL REFERENCES polls (id) ON DELETE CASCADE,
+    user_id bigint NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    option_ids bigint [] NOT NULL,
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (poll_id, user_id)
+);
@@ -0,0 +1,6 @@
+ALTER TABLE posts
+ADD COLUMN visibility varchar(20) NOT NULL DEFAULT 'public' CHECK (
+        visibility IN ('public', 'followers', 'unlisted', 'private')
+    );
+CREATE INDEX IF NOT EXISTS idx_posts_user_id_visibility ON posts (user_id, visibility);
+CREATE INDEX IF NOT EXISTS idx_followers_follower_id ON followers (follower_id);
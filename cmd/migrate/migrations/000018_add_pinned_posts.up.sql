@@ -0,0 +1,7 @@
+CREATE TABLE IF NOT EXISTS pinned_posts (
+    user_id bigint NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    post_id bigint NOT NULL UNIQUE REFERENCES posts (id) ON DELETE CASCADE,
+    position int NOT NULL,
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (user_id, post_id)
+);
@@ -0,0 +1,3 @@
+ALTER TABLE users DROP COLUMN sensitive_content;
+ALTER TABLE posts DROP COLUMN sensitive;
+ALTER TABLE posts DROP COLUMN content_warning;
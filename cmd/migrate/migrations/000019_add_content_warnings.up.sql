@@ -0,0 +1,8 @@
+ALTER TABLE posts
+ADD COLUMN content_warning varchar(200) NOT NULL DEFAULT '';
+ALTER TABLE posts
+ADD COLUMN sensitive boolean NOT NULL DEFAULT FALSE;
+ALTER TABLE users
+ADD COLUMN sensitive_content varchar(20) NOT NULL DEFAULT 'collapse' CHECK (
+        sensitive_content IN ('expand', 'collapse', 'hide')
+    );
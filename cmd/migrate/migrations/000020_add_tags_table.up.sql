@@ -0,0 +1,42 @@
+CREATE TABLE IF NOT EXISTS tags (
+    id bigserial PRIMARY KEY,
+    slug varchar(100) NOT NULL UNIQUE,
+    name varchar(100) NOT NULL,
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS tag_aliases (
+    alias varchar(100) PRIMARY KEY,
+    tag_id bigint NOT NULL REFERENCES tags (id) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS idx_tag_aliases_tag_id ON tag_aliases (tag_id);
+
+-- Existing free-form tags are rewritten to their slugs: lower cased, trimmed,
+-- without a leading '#' and with inner whitespace collapsed to '-'.
+UPDATE posts
+SET tags = ARRAY(
+        SELECT t.slug
+        FROM (
+                SELECT regexp_replace(lower(btrim(ltrim(btrim(x.tag), '#'))), '\s+', '-', 'g') AS slug,
+                    x.n
+                FROM unnest(posts.tags) WITH ORDINALITY AS x(tag, n)
+            ) t
+        WHERE t.slug <> ''
+        GROUP BY t.slug
+        ORDER BY MIN(t.n)
+    )
+WHERE tags IS NOT NULL;
+
+UPDATE hashtags
+SET tag = regexp_replace(lower(btrim(ltrim(btrim(tag), '#'))), '\s+', '-', 'g');
+DELETE FROM hashtags WHERE tag = '';
+
+INSERT INTO tags (slug, name)
+SELECT DISTINCT t.tag, t.tag
+FROM posts, unnest(posts.tags) AS t(tag)
+ON CONFLICT (slug) DO NOTHING;
+
+INSERT INTO tags (slug, name)
+SELECT DISTINCT tag, tag
+FROM hashtags
+ON CONFLICT (slug) DO NOTHING;
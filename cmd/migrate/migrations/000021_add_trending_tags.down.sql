@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_posts_created_at;
+DROP INDEX IF EXISTS idx_tags_slug_prefix;
+DROP INDEX IF EXISTS idx_tags_slug_trgm;
+DROP TABLE IF EXISTS trending_tags;
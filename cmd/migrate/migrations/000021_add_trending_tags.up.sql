@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS trending_tags (
+    time_window varchar(20) NOT NULL,
+    slug varchar(100) NOT NULL,
+    score double precision NOT NULL,
+    post_count int NOT NULL,
+    refreshed_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (time_window, slug)
+);
+CREATE INDEX IF NOT EXISTS idx_trending_tags_score ON trending_tags (time_window, score DESC);
+
+CREATE INDEX IF NOT EXISTS idx_tags_slug_trgm ON tags USING gin (slug gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS idx_tags_slug_prefix ON tags (slug varchar_pattern_ops);
+CREATE INDEX IF NOT EXISTS idx_posts_created_at ON posts (created_at);
@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS post_stats_daily (
+    post_id bigint NOT NULL REFERENCES posts (id) ON DELETE CASCADE,
+    day date NOT NULL,
+    views bigint NOT NULL DEFAULT 0,
+    impressions bigint NOT NULL DEFAULT 0,
+    PRIMARY KEY (post_id, day)
+);
+
+CREATE INDEX IF NOT EXISTS idx_post_stats_daily_day ON post_stats_daily (day);
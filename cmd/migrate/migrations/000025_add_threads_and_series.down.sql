@@ -0,0 +1,11 @@
+DROP INDEX IF EXISTS idx_posts_series;
+DROP INDEX IF EXISTS idx_posts_thread;
+
+ALTER TABLE posts
+DROP COLUMN IF EXISTS series_position,
+DROP COLUMN IF EXISTS series_id,
+DROP COLUMN IF EXISTS thread_position,
+DROP COLUMN IF EXISTS thread_id;
+
+DROP TABLE IF EXISTS series;
+DROP TABLE IF EXISTS threads;
@@ -0,0 +1,24 @@
+CREATE TABLE IF NOT EXISTS threads (
+    id bigserial PRIMARY KEY,
+    user_id bigint NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS series (
+    id bigserial PRIMARY KEY,
+    user_id bigint NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    title varchar(100) NOT NULL,
+    description varchar(500) NOT NULL DEFAULT '',
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_series_user_id ON series (user_id);
+
+ALTER TABLE posts
+ADD COLUMN IF NOT EXISTS thread_id bigint REFERENCES threads (id) ON DELETE SET NULL,
+ADD COLUMN IF NOT EXISTS thread_position int,
+ADD COLUMN IF NOT EXISTS series_id bigint REFERENCES series (id) ON DELETE SET NULL,
+ADD COLUMN IF NOT EXISTS series_position int;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_posts_thread ON posts (thread_id, thread_position) WHERE thread_id IS NOT NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_posts_series ON posts (series_id, series_position) WHERE series_id IS NOT NULL;
@@ -0,0 +1,21 @@
+CREATE TABLE IF NOT EXISTS link_previews (
+    url text PRIMARY KEY,
+    status varchar(10) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'ok', 'failed')),
+    title text NOT NULL DEFAULT '',
+    description text NOT NULL DEFAULT '',
+    image_url text NOT NULL DEFAULT '',
+    site_name text NOT NULL DEFAULT '',
+    fetched_at timestamp(0) with time zone,
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_link_previews_fetch ON link_previews (status, fetched_at);
+
+CREATE TABLE IF NOT EXISTS post_links (
+    post_id bigint NOT NULL REFERENCES posts (id) ON DELETE CASCADE,
+    url text NOT NULL REFERENCES link_previews (url) ON DELETE CASCADE,
+    position int NOT NULL,
+    PRIMARY KEY (post_id, url)
+);
+
+CREATE INDEX IF NOT EXISTS idx_post_links_url ON post_links (url);
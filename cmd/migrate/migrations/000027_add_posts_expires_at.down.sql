@@ -0,0 +1,5 @@
+ALTER TABLE comments DROP CONSTRAINT IF EXISTS fk_comments_post;
+
+DROP INDEX IF EXISTS idx_posts_expires_at;
+
+ALTER TABLE posts DROP COLUMN IF EXISTS expires_at;
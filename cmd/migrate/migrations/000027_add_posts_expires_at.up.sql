@@ -0,0 +1,9 @@
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS expires_at timestamp(0) with time zone;
+
+CREATE INDEX IF NOT EXISTS idx_posts_expires_at ON posts (expires_at) WHERE expires_at IS NOT NULL;
+
+-- Comments never referenced their post, so deleting a post left them behind.
+DELETE FROM comments c WHERE NOT EXISTS (SELECT 1 FROM posts p WHERE p.id = c.post_id);
+
+ALTER TABLE comments
+ADD CONSTRAINT fk_comments_post FOREIGN KEY (post_id) REFERENCES posts (id) ON DELETE CASCADE;
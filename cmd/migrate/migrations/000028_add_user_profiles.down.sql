@@ -0,0 +1,8 @@
+DROP TABLE IF EXISTS user_avatars;
+
+ALTER TABLE users
+DROP COLUMN IF EXISTS avatar_url,
+DROP COLUMN IF EXISTS links,
+DROP COLUMN IF EXISTS location,
+DROP COLUMN IF EXISTS bio,
+DROP COLUMN IF EXISTS display_name;
@@ -0,0 +1,13 @@
+ALTER TABLE users
+ADD COLUMN IF NOT EXISTS display_name varchar(50) NOT NULL DEFAULT '',
+ADD COLUMN IF NOT EXISTS bio varchar(300) NOT NULL DEFAULT '',
+ADD COLUMN IF NOT EXISTS location varchar(100) NOT NULL DEFAULT '',
+ADD COLUMN IF NOT EXISTS links text[] NOT NULL DEFAULT '{}',
+ADD COLUMN IF NOT EXISTS avatar_url text NOT NULL DEFAULT '';
+
+CREATE TABLE IF NOT EXISTS user_avatars (
+    user_id bigint PRIMARY KEY REFERENCES users (id) ON DELETE CASCADE,
+    content_type varchar(50) NOT NULL,
+    data bytea NOT NULL,
+    updated_at timestamp(0) with time zone NOT NULL DEFAULT NOW()
+);
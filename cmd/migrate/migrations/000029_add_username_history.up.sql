@@ -0,0 +1,12 @@
+ALTER TABLE users ADD COLUMN IF NOT EXISTS username_changed_at timestamp(0) with time zone;
+
+CREATE TABLE IF NOT EXISTS username_history (
+    id bigserial PRIMARY KEY,
+    user_id bigint NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    username varchar(255) NOT NULL,
+    changed_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+    reserved_until timestamp(0) with time zone NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_username_history_username ON username_history (username, reserved_until DESC);
+CREATE INDEX IF NOT EXISTS idx_username_history_user_id ON username_history (user_id);
@@ -0,0 +1,6 @@
+ALTER TABLE users ADD COLUMN IF NOT EXISTS suspended_at timestamp(0) with time zone;
+
+CREATE INDEX IF NOT EXISTS idx_users_username_prefix ON users (lower(username) varchar_pattern_ops);
+CREATE INDEX IF NOT EXISTS idx_users_display_name_prefix ON users (lower(display_name) varchar_pattern_ops);
+CREATE INDEX IF NOT EXISTS idx_users_username_trgm ON users USING gin (username gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS idx_users_display_name_trgm ON users USING gin (display_name gin_trgm_ops);
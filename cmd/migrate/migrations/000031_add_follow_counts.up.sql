@@ -0,0 +1,31 @@
+ALTER TABLE users
+ADD COLUMN IF NOT EXISTS followers_count int NOT NULL DEFAULT 0,
+ADD COLUMN IF NOT EXISTS following_count int NOT NULL DEFAULT 0;
+
+UPDATE users u SET
+    followers_count = (SELECT COUNT(*) FROM followers f WHERE f.user_id = u.id),
+    following_count = (SELECT COUNT(*) FROM followers f WHERE f.follower_id = u.id);
+
+-- Counters are kept by a trigger so follows removed through cascades are
+-- counted too.
+CREATE OR REPLACE FUNCTION update_follow_counts() RETURNS trigger AS $$
+BEGIN
+    IF TG_OP = 'INSERT' THEN
+        UPDATE users SET followers_count = followers_count + 1 WHERE id = NEW.user_id;
+        UPDATE users SET following_count = following_count + 1 WHERE id = NEW.follower_id;
+        RETURN NEW;
+    END IF;
+
+    UPDATE users SET followers_count = followers_count - 1 WHERE id = OLD.user_id;
+    UPDATE users SET following_count = following_count - 1 WHERE id = OLD.follower_id;
+    RETURN OLD;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS trg_follow_counts ON followers;
+CREATE TRIGGER trg_follow_counts
+AFTER INSERT OR DELETE ON followers
+FOR EACH ROW EXECUTE FUNCTION update_follow_counts();
+
+CREATE INDEX IF NOT EXISTS idx_followers_user_id_created_at ON followers (user_id, created_at DESC, follower_id DESC);
+CREATE INDEX IF NOT EXISTS idx_followers_follower_id_created_at ON followers (follower_id, created_at DESC, user_id DESC);
@@ -0,0 +1,19 @@
+CREATE TABLE IF NOT EXISTS blocks (
+    blocker_id bigint NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    blocked_id bigint NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (blocker_id, blocked_id),
+    CHECK (blocker_id <> blocked_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_blocks_blocker_id_created_at ON blocks (blocker_id, created_at DESC, blocked_id DESC);
+
+CREATE TABLE IF NOT EXISTS mutes (
+    muter_id bigint NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    muted_id bigint NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (muter_id, muted_id),
+    CHECK (muter_id <> muted_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_mutes_muter_id_created_at ON mutes (muter_id, created_at DESC, muted_id DESC);
@@ -0,0 +1,4 @@
+ALTER TABLE users ADD COLUMN IF NOT EXISTS deletion_requested_at timestamp(0) with time zone;
+
+CREATE INDEX IF NOT EXISTS idx_users_deletion_requested_at ON users (deletion_requested_at)
+WHERE deletion_requested_at IS NOT NULL;
@@ -0,0 +1,16 @@
+CREATE TABLE IF NOT EXISTS data_exports (
+    id bigserial PRIMARY KEY,
+    user_id bigint NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    status varchar(20) NOT NULL DEFAULT 'pending',
+    archive bytea,
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+    started_at timestamp(0) with time zone,
+    completed_at timestamp(0) with time zone,
+    expires_at timestamp(0) with time zone
+);
+
+CREATE INDEX IF NOT EXISTS idx_data_exports_status_created_at ON data_exports (status, created_at);
+
+-- One export in flight per user.
+CREATE UNIQUE INDEX IF NOT EXISTS idx_data_exports_user_id_in_flight ON data_exports (user_id)
+WHERE status IN ('pending', 'processing');
@@ -0,0 +1,10 @@
+ALTER TABLE users
+ADD COLUMN IF NOT EXISTS sensitive_content varchar(20) NOT NULL DEFAULT 'collapse' CHECK (
+    sensitive_content IN ('expand', 'collapse', 'hide')
+);
+
+UPDATE users u SET sensitive_content = s.settings->>'sensitive_content'
+FROM user_settings s
+WHERE s.user_id = u.id AND s.settings->>'sensitive_content' IN ('expand', 'collapse', 'hide');
+
+DROP TABLE IF EXISTS user_settings;
@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS user_settings (
+    user_id bigint PRIMARY KEY REFERENCES users (id) ON DELETE CASCADE,
+    settings jsonb NOT NULL DEFAULT '{}',
+    version int NOT NULL DEFAULT 1,
+    updated_at timestamp(0) with time zone NOT NULL DEFAULT NOW()
+);
+
+-- Only users who moved away from the default have anything to carry over.
+INSERT INTO user_settings (user_id, settings)
+SELECT id, jsonb_build_object('sensitive_content', sensitive_content)
+FROM users
+WHERE sensitive_content <> 'collapse'
+ON CONFLICT (user_id) DO NOTHING;
+
+ALTER TABLE users DROP COLUMN IF EXISTS sensitive_content;
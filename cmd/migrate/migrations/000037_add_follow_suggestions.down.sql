@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_users_followers_count;
+DROP TABLE IF EXISTS suggestion_dismissals;
+DROP TABLE IF EXISTS follow_suggestions;
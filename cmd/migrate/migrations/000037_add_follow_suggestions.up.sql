@@ -0,0 +1,23 @@
+CREATE TABLE IF NOT EXISTS follow_suggestions (
+    user_id bigint NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    suggested_id bigint NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    score double precision NOT NULL,
+    mutual_count int NOT NULL,
+    shared_tags int NOT NULL,
+    computed_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (user_id, suggested_id)
+);
+CREATE INDEX IF NOT EXISTS idx_follow_suggestions_score ON follow_suggestions (user_id, score DESC);
+CREATE INDEX IF NOT EXISTS idx_follow_suggestions_suggested_id ON follow_suggestions (suggested_id);
+
+CREATE TABLE IF NOT EXISTS suggestion_dismissals (
+    user_id bigint NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    dismissed_id bigint NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (user_id, dismissed_id),
+    CHECK (user_id <> dismissed_id)
+);
+CREATE INDEX IF NOT EXISTS idx_suggestion_dismissals_dismissed_id ON suggestion_dismissals (dismissed_id);
+
+-- Popular accounts are the fallback for users without a graph yet.
+CREATE INDEX IF NOT EXISTS idx_users_followers_count ON users (followers_count DESC, id);
@@ -0,0 +1,17 @@
+-- Tags given explicitly are kept apart from the ones derived from #hashtags
+-- in the content, so the derived ones can be recomputed on every edit.
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS explicit_tags varchar(100) [] NOT NULL DEFAULT '{}';
+
+-- Existing posts only store the union. Tags matching a hashtag still in the
+-- content are taken as derived, the rest as explicit.
+UPDATE posts p
+SET explicit_tags = ARRAY(
+        SELECT x.tag
+        FROM unnest(p.tags) WITH ORDINALITY AS x(tag, n)
+        WHERE x.tag NOT IN (
+                SELECT lower(m[1])
+                FROM regexp_matches(p.content, '(?:^|[^\w#&/])#(\w{1,100})', 'g') AS m
+            )
+        ORDER BY x.n
+    )
+WHERE p.tags IS NOT NULL AND p.tags <> '{}';
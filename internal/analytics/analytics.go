@@ -0,0 +1,130 @@
+// Package analytics counts post views and impressions in memory so the
+// request path never writes to the database. Counts are deduplicated per
+// viewer within a time window and drained in batches by a background job.
+package analytics
+
+import (
+	"sync"
+	"time"
+
+	"github.com/Aiyanu/gophersocial/internal/store"
+)
+
+type Kind int
+
+const (
+	// View is a post opened on its own page.
+	View Kind = iota
+	// Impression is a post shown in a listing such as the feed.
+	Impression
+)
+
+type seenKey struct {
+	kind     Kind
+	postID   int64
+	viewerID int64
+}
+
+type countKey struct {
+	postID int64
+	day    time.Time
+}
+
+type counts struct {
+	views       int64
+	impressions int64
+}
+
+// Counter buffers post stats until they are drained. It is safe for
+// concurrent use.
+type Counter struct {
+	window time.Duration
+
+	mu      sync.Mutex
+	bucket  time.Time
+	seen    map[seenKey]struct{}
+	pending map[countKey]*counts
+}
+
+// NewCounter returns a Counter that counts a viewer at most once per post
+// and kind within each window.
+func NewCounter(window time.Duration) *Counter {
+	return &Counter{
+		window:  window,
+		seen:    map[seenKey]struct{}{},
+		pending: map[countKey]*counts{},
+	}
+}
+
+// Record counts one view or impression of postID by viewerID unless the
+// viewer was already counted in the current window.
+func (c *Counter) Record(kind Kind, postID, viewerID int64) {
+	now := time.Now().UTC()
+	bucket := now.Truncate(c.window)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// Only the current window needs to be remembered, which keeps the set
+	// bounded by the distinct viewers of a single window.
+	if !bucket.Equal(c.bucket) {
+		c.bucket = bucket
+		c.seen = map[seenKey]struct{}{}
+	}
+
+	key := seenKey{kind, postID, viewerID}
+	if _, ok := c.seen[key]; ok {
+		return
+	}
+	c.seen[key] = struct{}{}
+
+	day := now.Truncate(24 * time.Hour)
+	n, ok := c.pending[countKey{postID, day}]
+	if !ok {
+		n = &counts{}
+		c.pending[countKey{postID, day}] = n
+	}
+	switch kind {
+	case View:
+		n.views++
+	case Impression:
+		n.impressions++
+	}
+}
+
+// Drain returns the counts buffered so far and resets them.
+func (c *Counter) Drain() []store.PostStatsDelta {
+	c.mu.Lock()
+	pending := c.pending
+	c.pending = map[countKey]*counts{}
+	c.mu.Unlock()
+
+	deltas := make([]store.PostStatsDelta, 0, len(pending))
+	for k, n := range pending {
+		deltas = append(deltas, store.PostStatsDelta{
+			PostID:      k.postID,
+			Day:         k.day,
+			Views:       n.views,
+			Impressions: n.impressions,
+		})
+	}
+	return deltas
+}
+
+// Restore puts back deltas that could not be flushed so they are retried
+// with the next batch.
+func (c *Counter) Restore(deltas []store.PostStatsDelta) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, d := range deltas {
+		key := countKey{d.PostID, d.Day}
+		n, ok := c.pending[key]
+		if !ok {
+			n = &counts{}
+			c.pending[key] = n
+		}
+		n.views += d.Views
+		n.impressions += d.Impressions
+	}
+}
@@ -8,6 +8,7 @@ import (
 
 	"math/rand"
 
+	"github.com/Aiyanu/gophersocial/internal/markdown"
 	"github.com/Aiyanu/gophersocial/internal/store"
 )
 
@@ -129,11 +130,14 @@ func generatePosts(num int, users []*store.User) []*store.Post {
 	posts := make([]*store.Post, num)
 	for i := 0; i < num; i++ {
 		user := users[rand.Intn(len(users))]
+		content := titles[rand.Intn(len(contents))]
+		contentHTML, _ := markdown.Render(content)
 
 		posts[i] = &store.Post{
-			UserID:  user.ID,
-			Title:   titles[rand.Intn(len(titles))],
-			Content: titles[rand.Intn(len(contents))],
+			UserID:      user.ID,
+			Title:       titles[rand.Intn(len(titles))],
+			Content:     content,
+			ContentHTML: contentHTML,
 			Tags: []string{
 				tags[rand.Intn(len(tags))],
 				tags[rand.Intn(len(tags))],
@@ -147,10 +151,14 @@ func generatePosts(num int, users []*store.User) []*store.Post {
 func generateComments(num int, users []*store.User, posts []*store.Post) []*store.Comment {
 	cms := make([]*store.Comment, num)
 	for i := 0; i < num; i++ {
+		content := comments[rand.Intn(len(comments))]
+		contentHTML, _ := markdown.Render(content)
+
 		cms[i] = &store.Comment{
-			PostID:  posts[rand.Intn(len(posts))].ID,
-			UserID:  users[rand.Intn(len(users))].ID,
-			Content: comments[rand.Intn(len(comments))],
+			PostID:      posts[rand.Intn(len(posts))].ID,
+			UserID:      users[rand.Intn(len(users))].ID,
+			Content:     content,
+			ContentHTML: contentHTML,
 		}
 	}
 	return cms
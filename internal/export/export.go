@@ -0,0 +1,117 @@
+// Package export packs the data held about a user into a zip archive of
+// JSON files, for personal data requests.
+package export
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/Aiyanu/gophersocial/internal/store"
+)
+
+var avatarExtensions = map[string]string{
+	"image/png":  ".png",
+	"image/jpeg": ".jpg",
+	"image/gif":  ".gif",
+}
+
+const readme = `GopherSocial data export
+Generated %s for @%s.
+
+profile.json         your account, profile, settings and previous usernames
+posts.json           your posts. Only the latest version of a post is kept,
+                     "version" counts how many times it was edited
+comments.json        your comments
+follows.json         followers, accounts you follow and pending follow requests
+poll_votes.json      your votes on polls
+blocks.json          accounts you block
+mutes.json           accounts you mute
+media/               your avatar, if you uploaded one
+
+Sign-ins use short-lived tokens that are not stored, so there is no session
+history to export.
+`
+
+type profile struct {
+	*store.User
+	UsernameHistory []store.ExportedUsername `json:"username_history"`
+	Settings        *store.UserSettings      `json:"settings"`
+}
+
+type follows struct {
+	Followers        []store.ExportedRelation `json:"followers"`
+	Following        []store.ExportedRelation `json:"following"`
+	RequestsSent     []store.ExportedRelation `json:"requests_sent"`
+	RequestsReceived []store.ExportedRelation `json:"requests_received"`
+}
+
+// Archive builds the zip archive of data.
+func Archive(data *store.UserData, generatedAt time.Time) ([]byte, error) {
+	buf := new(bytes.Buffer)
+	zw := zip.NewWriter(buf)
+
+	w, err := create(zw, "README.txt", generatedAt)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := fmt.Fprintf(w, readme, generatedAt.UTC().Format(time.RFC1123), data.Profile.Username); err != nil {
+		return nil, err
+	}
+
+	files := []struct {
+		name string
+		v    any
+	}{
+		{"profile.json", profile{User: data.Profile, UsernameHistory: data.UsernameHistory, Settings: data.Settings}},
+		{"posts.json", data.Posts},
+		{"comments.json", data.Comments},
+		{"follows.json", follows{
+			Followers:        data.Followers,
+			Following:        data.Following,
+			RequestsSent:     data.RequestsSent,
+			RequestsReceived: data.RequestsReceived,
+		}},
+		{"poll_votes.json", data.PollVotes},
+		{"blocks.json", data.Blocks},
+		{"mutes.json", data.Mutes},
+	}
+	for _, f := range files {
+		w, err := create(zw, f.name, generatedAt)
+		if err != nil {
+			return nil, err
+		}
+
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(f.v); err != nil {
+			return nil, fmt.Errorf("encoding %s: %w", f.name, err)
+		}
+	}
+
+	if data.Avatar != nil {
+		w, err := create(zw, "media/avatar"+avatarExtensions[data.Avatar.ContentType], generatedAt)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := w.Write(data.Avatar.Data); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func create(zw *zip.Writer, name string, modified time.Time) (io.Writer, error) {
+	return zw.CreateHeader(&zip.FileHeader{
+		Name:     name,
+		Method:   zip.Deflate,
+		Modified: modified,
+	})
+}
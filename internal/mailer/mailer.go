@@ -3,9 +3,11 @@ package mailer
 import "embed"
 
 const (
-	FromName            = "GopherSocial"
-	maxRetries          = 3
-	UserWelcomeTemplate = "user_invitation.tmpl"
+	FromName                = "GopherSocial"
+	maxRetries              = 3
+	UserWelcomeTemplate     = "user_invitation.tmpl"
+	AccountDeletionTemplate = "account_deletion.tmpl"
+	DataExportTemplate      = "data_export.tmpl"
 )
 
 //go:embed "templates"
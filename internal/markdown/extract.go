@@ -0,0 +1,57 @@
+package markdown
+
+import (
+	"regexp"
+	"strings"
+)
+
+var (
+	mentionRx = regexp.MustCompile(`(?:^|[^\w@])@(\w{1,100})`)
+	hashtagRx = regexp.MustCompile(`(?:^|[^\w#&/])#([\p{L}\p{N}_]{1,100})`)
+	linkRx    = regexp.MustCompile(`(?i)\b(https?://[^\s<>"'()\[\]]{1,1000})`)
+)
+
+// Mentions returns the distinct usernames referenced as @username in src.
+func Mentions(src string) []string {
+	return extract(mentionRx, src, false)
+}
+
+// Hashtags returns the distinct hashtags referenced as #tag in src, lower
+// cased and without the leading '#'.
+func Hashtags(src string) []string {
+	return extract(hashtagRx, src, true)
+}
+
+// Links returns the distinct http and https URLs found in src, without
+// trailing punctuation.
+func Links(src string) []string {
+	seen := map[string]bool{}
+	links := []string{}
+	for _, link := range extract(linkRx, src, false) {
+		link = strings.TrimRight(link, ".,;:!?*_~")
+		if seen[link] {
+			continue
+		}
+		seen[link] = true
+		links = append(links, link)
+	}
+	return links
+}
+
+func extract(rx *regexp.Regexp, src string, lower bool) []string {
+	seen := map[string]bool{}
+	found := []string{}
+
+	for _, m := range rx.FindAllStringSubmatch(src, -1) {
+		v := m[1]
+		if lower {
+			v = strings.ToLower(v)
+		}
+		if seen[v] {
+			continue
+		}
+		seen[v] = true
+		found = append(found, v)
+	}
+	return found
+}
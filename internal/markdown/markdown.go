@@ -0,0 +1,26 @@
+package markdown
+
+import (
+	"bytes"
+
+	"github.com/microcosm-cc/bluemonday"
+	"github.com/yuin/goldmark"
+)
+
+var (
+	md = goldmark.New()
+
+	// policy allows the usual user generated content markup and drops
+	// scripts, inline event handlers and non http(s)/mailto URLs.
+	policy = bluemonday.UGCPolicy()
+)
+
+// Render converts CommonMark source into sanitised HTML.
+func Render(src string) (string, error) {
+	var buf bytes.Buffer
+	if err := md.Convert([]byte(src), &buf); err != nil {
+		return "", err
+	}
+
+	return policy.Sanitize(buf.String()), nil
+}
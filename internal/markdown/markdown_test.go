@@ -0,0 +1,66 @@
+package markdown
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRender(t *testing.T) {
+	tests := []struct {
+		name    string
+		src     string
+		want    string
+		notWant []string
+	}{
+		{
+			name: "commonmark",
+			src:  "# Title\n\nSome *emphasis* and a [link](https://example.com).",
+			want: `<h1>Title</h1>
+<p>Some <em>emphasis</em> and a <a href="https://example.com" rel="nofollow">link</a>.</p>
+`,
+		},
+		{
+			name:    "script tag",
+			src:     "Hello <script>alert(1)</script>",
+			notWant: []string{"<script"},
+		},
+		{
+			name:    "event handler",
+			src:     `<img src="https://example.com/a.png" onerror="alert(1)">`,
+			notWant: []string{"onerror", "alert(1)"},
+		},
+		{
+			name:    "javascript link",
+			src:     "[click](javascript:alert(1))",
+			notWant: []string{"javascript:"},
+		},
+		{
+			name:    "javascript image",
+			src:     "![pic](javascript:alert(1))",
+			notWant: []string{"javascript:"},
+		},
+		{
+			name:    "raw html",
+			src:     `<div style="position:fixed"><iframe src="https://example.com"></iframe></div>`,
+			notWant: []string{"<div", "<iframe", "style="},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Render(tt.src)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if tt.want != "" && got != tt.want {
+				t.Errorf("Render() = %q, want %q", got, tt.want)
+			}
+			for _, s := range tt.notWant {
+				if strings.Contains(got, s) {
+					t.Errorf("Render() = %q, want no %q", got, s)
+				}
+			}
+		})
+	}
+}
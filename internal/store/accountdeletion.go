@@ -0,0 +1,132 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// accountPurgeBatchSize bounds how many accounts a single purge run deletes.
+const accountPurgeBatchSize = 100
+
+// RequestDeletion deactivates a user straight away and starts the grace
+// period after which PurgeDeleted removes the account. It returns when the
+// request was recorded.
+func (s *UserStore) RequestDeletion(ctx context.Context, userID int64) (time.Time, error) {
+	query := `
+		UPDATE users SET is_active = false, deletion_requested_at = NOW()
+		WHERE id = $1 AND is_active = true
+		RETURNING deletion_requested_at
+	`
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	var requestedAt time.Time
+	if err := s.db.QueryRowContext(ctx, query, userID).Scan(&requestedAt); err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return time.Time{}, ErrNotFound
+		default:
+			return time.Time{}, err
+		}
+	}
+	return requestedAt, nil
+}
+
+// Restore cancels a pending deletion requested less than grace ago and
+// reactivates the account.
+func (s *UserStore) Restore(ctx context.Context, userID int64, grace time.Duration) error {
+	query := `
+		UPDATE users SET is_active = true, deletion_requested_at = NULL
+		WHERE id = $1 AND deletion_requested_at > NOW() - make_interval(secs => $2)
+	`
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	result, err := s.db.ExecContext(ctx, query, userID, grace.Seconds())
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// PurgeDeleted hard-deletes the accounts whose deletion was requested more
+// than grace ago, one transaction per account. Nothing of the account is
+// kept:
+//
+//   - its posts go, and with them their comments, polls, pins, mentions,
+//     hashtags, link references and stats;
+//   - its comments on other users' posts are deleted;
+//   - follows, follow requests, blocks and mutes in either direction, poll
+//     votes, threads, series, the avatar and username history are removed
+//     with the user row. Other users' posts in its series stay, detached;
+//   - pending invitations are deleted.
+func (s *UserStore) PurgeDeleted(ctx context.Context, grace time.Duration) error {
+	query := `
+		SELECT id FROM users
+		WHERE deletion_requested_at <= NOW() - make_interval(secs => $1)
+		ORDER BY deletion_requested_at
+		LIMIT $2
+	`
+	listCtx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(listCtx, query, grace.Seconds(), accountPurgeBatchSize)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	ids := []int64{}
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return err
+		}
+		ids = append(ids, id)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, id := range ids {
+		if err := s.purge(ctx, id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *UserStore) purge(ctx context.Context, userID int64) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	return withTx(s.db, ctx, func(tx *sql.Tx) error {
+		// Followers go first so the counter trigger still finds the other
+		// side's row to update.
+		queries := []string{
+			`DELETE FROM followers WHERE user_id = $1 OR follower_id = $1`,
+			`DELETE FROM comments WHERE user_id = $1`,
+			`DELETE FROM posts WHERE user_id = $1`,
+		}
+		for _, query := range queries {
+			if _, err := tx.ExecContext(ctx, query, userID); err != nil {
+				return err
+			}
+		}
+
+		if err := s.deleteUserInvitations(ctx, tx, userID); err != nil {
+			return err
+		}
+		return s.delete(ctx, tx, userID)
+	})
+}
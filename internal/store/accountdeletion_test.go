@@ -0,0 +1,43 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestAccountPendingDeletionIsHidden(t *testing.T) {
+	db := newTestDB(t)
+	ctx := context.Background()
+	users := &UserStore{db}
+
+	author := createTestUser(t, db, "author")
+	leaving := createTestUser(t, db, "leaving")
+	post := createTestPost(t, db, author.ID, VisibilityPublic)
+
+	if err := users.SetAvatar(ctx, leaving.ID, "image/png", []byte("png"), "/v1/users/avatar"); err != nil {
+		t.Fatal(err)
+	}
+	comment := &Comment{PostID: post.ID, UserID: leaving.ID, Content: "Bye"}
+	if err := (&CommentStore{db}).Create(ctx, comment); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := users.RequestDeletion(ctx, leaving.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, _, err := users.GetAvatar(ctx, leaving.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("GetAvatar: got %v, want ErrNotFound", err)
+	}
+
+	comments, err := (&CommentStore{db}).GetByPostID(ctx, post.ID, author.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range comments {
+		if c.ID == comment.ID {
+			t.Errorf("comment %d of an account pending deletion is listed", c.ID)
+		}
+	}
+}
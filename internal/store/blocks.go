@@ -0,0 +1,153 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// BlockListEntry is an account on a user's block or mute list.
+type BlockListEntry struct {
+	ID          int64     `json:"id"`
+	Username    string    `json:"username"`
+	DisplayName string    `json:"display_name"`
+	AvatarURL   string    `json:"avatar_url"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+type BlockStore struct {
+	db *sql.DB
+}
+
+// blockedBetween is an SQL condition that holds when either of the users
+// given as SQL expressions a and b blocks the other.
+func blockedBetween(a, b string) string {
+	return fmt.Sprintf(`EXISTS (
+		SELECT 1 FROM blocks bx
+		WHERE (bx.blocker_id = %[1]s AND bx.blocked_id = %[2]s) OR
+			(bx.blocker_id = %[2]s AND bx.blocked_id = %[1]s)
+	)`, a, b)
+}
+
+// Block makes blockerID block blockedID, dropping any follow or pending
+// follow request between the two in either direction. Blocking an unknown
+// user gives ErrNotFound.
+func (s *BlockStore) Block(ctx context.Context, blockerID, blockedID int64) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	return withTx(s.db, ctx, func(tx *sql.Tx) error {
+		query := `
+			INSERT INTO blocks (blocker_id, blocked_id) VALUES ($1, $2)
+			ON CONFLICT DO NOTHING
+		`
+		if _, err := tx.ExecContext(ctx, query, blockerID, blockedID); err != nil {
+			if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23503" {
+				return ErrNotFound
+			}
+			return err
+		}
+
+		queries := []string{
+			`DELETE FROM followers WHERE (user_id = $1 AND follower_id = $2) OR (user_id = $2 AND follower_id = $1)`,
+			`DELETE FROM follow_requests WHERE (user_id = $1 AND requester_id = $2) OR (user_id = $2 AND requester_id = $1)`,
+		}
+		for _, query := range queries {
+			if _, err := tx.ExecContext(ctx, query, blockerID, blockedID); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (s *BlockStore) Unblock(ctx context.Context, blockerID, blockedID int64) error {
+	query := `DELETE FROM blocks WHERE blocker_id = $1 AND blocked_id = $2`
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	result, err := s.db.ExecContext(ctx, query, blockerID, blockedID)
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// IsBlocked reports whether either user blocks the other.
+func (s *BlockStore) IsBlocked(ctx context.Context, userID, otherID int64) (bool, error) {
+	query := `SELECT ` + blockedBetween("$1::bigint", "$2::bigint")
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	var blocked bool
+	err := s.db.QueryRowContext(ctx, query, userID, otherID).Scan(&blocked)
+	return blocked, err
+}
+
+// GetBlocked lists the accounts userID blocks, most recent first.
+func (s *BlockStore) GetBlocked(ctx context.Context, userID int64, cursor string, limit int) ([]BlockListEntry, string, error) {
+	return listBlockEntries(ctx, s.db, "blocks", "blocker_id", "blocked_id", userID, cursor, limit)
+}
+
+// listBlockEntries pages through the rows of table whose ownerCol is userID,
+// returning the accounts in otherCol with the cursor of the next page.
+func listBlockEntries(ctx context.Context, db *sql.DB, table, ownerCol, otherCol string, userID int64, cursor string, limit int) ([]BlockListEntry, string, error) {
+	var cursorAt *time.Time
+	var cursorID *int64
+	if cursor != "" {
+		c, err := DecodeCursor(cursor)
+		if err != nil {
+			return nil, "", err
+		}
+		cursorAt, cursorID = &c.CreatedAt, &c.ID
+	}
+
+	query := `
+		SELECT u.id, u.username, u.display_name, u.avatar_url, x.created_at
+		FROM ` + table + ` x
+		JOIN users u ON u.id = x.` + otherCol + `
+		WHERE x.` + ownerCol + ` = $1 AND
+			($2::timestamptz IS NULL OR (x.created_at, x.` + otherCol + `) < ($2, $3::bigint))
+		ORDER BY x.created_at DESC, x.` + otherCol + ` DESC
+		LIMIT $4
+	`
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := db.QueryContext(ctx, query, userID, cursorAt, cursorID, limit+1)
+	if err != nil {
+		return nil, "", err
+	}
+	defer rows.Close()
+
+	entries := []BlockListEntry{}
+	for rows.Next() {
+		var e BlockListEntry
+		if err := rows.Scan(&e.ID, &e.Username, &e.DisplayName, &e.AvatarURL, &e.CreatedAt); err != nil {
+			return nil, "", err
+		}
+		entries = append(entries, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, "", err
+	}
+
+	if len(entries) <= limit {
+		return entries, "", nil
+	}
+
+	entries = entries[:limit]
+	last := entries[len(entries)-1]
+	return entries, Cursor{CreatedAt: last.CreatedAt, ID: last.ID}.Encode(), nil
+}
@@ -0,0 +1,51 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/Aiyanu/gophersocial/internal/store"
+	"github.com/redis/go-redis/v9"
+)
+
+type SettingsStore struct {
+	rdb *redis.Client
+}
+
+// SettingsExpTime is longer than UserExpTime since settings only change
+// through their own endpoint, which refreshes the entry.
+const SettingsExpTime = time.Minute * 10
+
+func settingsKey(userID int64) string {
+	return fmt.Sprintf("settings-%v", userID)
+}
+
+func (s *SettingsStore) Get(ctx context.Context, userID int64) (*store.UserSettings, error) {
+	data, err := s.rdb.Get(ctx, settingsKey(userID)).Result()
+	if err == redis.Nil {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	var settings store.UserSettings
+	if err := json.Unmarshal([]byte(data), &settings); err != nil {
+		return nil, err
+	}
+	return &settings, nil
+}
+
+func (s *SettingsStore) Set(ctx context.Context, userID int64, settings *store.UserSettings) error {
+	data, err := json.Marshal(settings)
+	if err != nil {
+		return err
+	}
+
+	return s.rdb.SetEx(ctx, settingsKey(userID), data, SettingsExpTime).Err()
+}
+
+func (s *SettingsStore) Delete(ctx context.Context, userID int64) error {
+	return s.rdb.Del(ctx, settingsKey(userID)).Err()
+}
@@ -11,11 +11,23 @@ type Storage struct {
 	Users interface {
 		Get(context.Context, int64) (*store.User, error)
 		Set(context.Context, *store.User) error
+		Delete(context.Context, int64) error
+	}
+	Settings interface {
+		Get(context.Context, int64) (*store.UserSettings, error)
+		Set(context.Context, int64, *store.UserSettings) error
+		Delete(context.Context, int64) error
+	}
+	UserSearch interface {
+		Get(ctx context.Context, viewerID int64, q string, limit int) ([]store.UserSummary, error)
+		Set(ctx context.Context, viewerID int64, q string, limit int, users []store.UserSummary) error
 	}
 }
 
 func NewRedisStorage(rbd *redis.Client) Storage {
 	return Storage{
-		Users: &UserStore{rbd},
+		Users:      &UserStore{rbd},
+		Settings:   &SettingsStore{rbd},
+		UserSearch: &UserSearchStore{rbd},
 	}
 }
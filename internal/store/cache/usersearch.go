@@ -0,0 +1,47 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/Aiyanu/gophersocial/internal/store"
+	"github.com/redis/go-redis/v9"
+)
+
+type UserSearchStore struct {
+	rdb *redis.Client
+}
+
+// UserSearchExpTime is short: results only need to survive the bursts of
+// identical requests an as-you-type client sends.
+const UserSearchExpTime = time.Second * 30
+
+func userSearchKey(viewerID int64, q string, limit int) string {
+	return fmt.Sprintf("user-search-%v-%v-%s", viewerID, limit, q)
+}
+
+func (s *UserSearchStore) Get(ctx context.Context, viewerID int64, q string, limit int) ([]store.UserSummary, error) {
+	data, err := s.rdb.Get(ctx, userSearchKey(viewerID, q, limit)).Result()
+	if err == redis.Nil {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	var users []store.UserSummary
+	if err := json.Unmarshal([]byte(data), &users); err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
+func (s *UserSearchStore) Set(ctx context.Context, viewerID int64, q string, limit int, users []store.UserSummary) error {
+	data, err := json.Marshal(users)
+	if err != nil {
+		return err
+	}
+
+	return s.rdb.SetEx(ctx, userSearchKey(viewerID, q, limit), data, UserSearchExpTime).Err()
+}
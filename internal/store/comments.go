@@ -10,25 +10,31 @@ type CommentStore struct {
 }
 
 type Comment struct {
-	ID        int64  `json:"id"`
-	PostID    int64  `json:"post_id"`
-	UserID    int64  `json:"user_id"`
-	Content   string `json:"content"`
-	CreatedAt string `json:"created_at"`
-	User      User   `json:"user"`
+	ID          int64    `json:"id"`
+	PostID      int64    `json:"post_id"`
+	UserID      int64    `json:"user_id"`
+	Content     string   `json:"content"`
+	ContentHTML string   `json:"content_html"`
+	CreatedAt   string   `json:"created_at"`
+	User        User     `json:"user"`
+	Mentions    []string `json:"-"`
+	Hashtags    []string `json:"-"`
 }
 
-func (s *CommentStore) GetByPostID(ctx context.Context, postID int64) ([]Comment, error) {
+// GetByPostID lists the comments on a post, leaving out those of users
+// blocking the viewer or blocked by them and of accounts pending deletion.
+func (s *CommentStore) GetByPostID(ctx context.Context, postID, viewerID int64) ([]Comment, error) {
 	query := `
-		SELECT c.id, c.post_id, c.user_id, c.content c.created_at, users.username, users.id FROM comments c
+		SELECT c.id, c.post_id, c.user_id, c.content, c.content_html, c.created_at, users.username, users.id FROM comments c
 		JOIN users on users.id = c.user_id
-		WHERE c.post_id = $1
+		WHERE c.post_id = $1 AND users.deletion_requested_at IS NULL AND
+			NOT ` + blockedBetween("$2", "c.user_id") + `
 		ORDER BY c.created_at DESC
 	`
 	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
 	defer cancel()
 
-	rows, err := s.db.QueryContext(ctx, query, postID)
+	rows, err := s.db.QueryContext(ctx, query, postID, viewerID)
 	if err != nil {
 		return nil, err
 	}
@@ -38,7 +44,7 @@ func (s *CommentStore) GetByPostID(ctx context.Context, postID int64) ([]Comment
 	for rows.Next() {
 		var c Comment
 		c.User = User{}
-		err := rows.Scan(&c.ID, &c.PostID, &c.UserID, &c.Content, &c.CreatedAt, &c.User.Username, &c.User.ID)
+		err := rows.Scan(&c.ID, &c.PostID, &c.UserID, &c.Content, &c.ContentHTML, &c.CreatedAt, &c.User.Username, &c.User.ID)
 		if err != nil {
 			return nil, err
 		}
@@ -50,17 +56,25 @@ func (s *CommentStore) GetByPostID(ctx context.Context, postID int64) ([]Comment
 
 func (s *CommentStore) Create(ctx context.Context, comment *Comment) error {
 	query := `
-	INSERT INTO comments(post_id,user_id,content)
-	VALUES ($1,$2,$3)
+	INSERT INTO comments(post_id,user_id,content,content_html)
+	VALUES ($1,$2,$3,$4)
 	RETURNING id, created_at
 	`
 	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
 	defer cancel()
 
-	err := s.db.QueryRowContext(ctx, query, comment.PostID, comment.UserID, comment.Content).Scan(&comment.ID, comment.CreatedAt)
+	return withTx(s.db, ctx, func(tx *sql.Tx) error {
+		hashtags, err := resolveTags(ctx, tx, comment.Hashtags)
+		if err != nil {
+			return err
+		}
+		comment.Hashtags = hashtags
 
-	if err != nil {
-		return nil
-	}
-	return nil
+		err = tx.QueryRowContext(ctx, query, comment.PostID, comment.UserID, comment.Content, comment.ContentHTML).Scan(&comment.ID, &comment.CreatedAt)
+		if err != nil {
+			return err
+		}
+
+		return syncMentions(ctx, tx, comment.PostID, &comment.ID, comment.Mentions, comment.Hashtags)
+	})
 }
@@ -0,0 +1,89 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	_ "github.com/lib/pq"
+)
+
+var testUserSeq atomic.Int64
+
+// newTestDB connects to the database at TEST_DB_ADDR, which must be migrated
+// up. Tests that need a database are skipped when it is unset.
+func newTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+
+	addr := os.Getenv("TEST_DB_ADDR")
+	if addr == "" {
+		t.Skip("TEST_DB_ADDR is not set")
+	}
+
+	db, err := sql.Open("postgres", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := db.PingContext(ctx); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+// createTestUser creates an active user with a unique name and password
+// "password", removed again when the test ends.
+func createTestUser(t *testing.T, db *sql.DB, name string) *User {
+	t.Helper()
+
+	n := testUserSeq.Add(1)
+	user := &User{
+		Username: fmt.Sprintf("%s_%d_%d", name, time.Now().UnixNano(), n),
+	}
+	user.Email = user.Username + "@example.com"
+	if err := user.Password.Set("password"); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	err := withTx(db, ctx, func(tx *sql.Tx) error {
+		return UserStore{db}.Create(ctx, tx, user)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if err := (&UserStore{db}).purge(context.Background(), user.ID); err != nil {
+			t.Errorf("deleting test user: %v", err)
+		}
+	})
+
+	if _, err := db.Exec(`UPDATE users SET is_active = true WHERE id = $1`, user.ID); err != nil {
+		t.Fatal(err)
+	}
+	user.IsActive = true
+	return user
+}
+
+// createTestPost creates a post by userID with the given visibility.
+func createTestPost(t *testing.T, db *sql.DB, userID int64, visibility string) *Post {
+	t.Helper()
+
+	post := &Post{
+		Title:      "Test post",
+		Content:    "Test content",
+		UserID:     userID,
+		Visibility: visibility,
+	}
+	if err := (&PostStore{db}).Create(context.Background(), post); err != nil {
+		t.Fatal(err)
+	}
+	return post
+}
@@ -0,0 +1,439 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+const (
+	ExportPending    = "pending"
+	ExportProcessing = "processing"
+	ExportReady      = "ready"
+	ExportFailed     = "failed"
+	ExportExpired    = "expired"
+)
+
+// exportStaleAfter is how long an export may stay processing before it is
+// assumed abandoned by a crashed worker and handed out again.
+const exportStaleAfter = time.Hour
+
+type DataExport struct {
+	ID          int64      `json:"id"`
+	UserID      int64      `json:"-"`
+	Status      string     `json:"status"`
+	CreatedAt   time.Time  `json:"created_at"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"`
+	DownloadURL string     `json:"download_url,omitempty"`
+}
+
+// UserData is everything held about a user, as gathered for an export.
+type UserData struct {
+	Profile          *User
+	Settings         *UserSettings
+	UsernameHistory  []ExportedUsername
+	Posts            []ExportedPost
+	Comments         []ExportedComment
+	Followers        []ExportedRelation
+	Following        []ExportedRelation
+	RequestsSent     []ExportedRelation
+	RequestsReceived []ExportedRelation
+	Blocks           []ExportedRelation
+	Mutes            []ExportedRelation
+	PollVotes        []ExportedPollVote
+	Avatar           *ExportedAvatar
+}
+
+type ExportedUsername struct {
+	Username  string    `json:"username"`
+	ChangedAt time.Time `json:"changed_at"`
+}
+
+type ExportedPost struct {
+	ID             int64      `json:"id"`
+	Title          string     `json:"title"`
+	Content        string     `json:"content"`
+	Tags           []string   `json:"tags"`
+	Visibility     string     `json:"visibility"`
+	ContentWarning string     `json:"content_warning"`
+	Sensitive      bool       `json:"sensitive"`
+	Version        int        `json:"version"`
+	ThreadID       *int64     `json:"thread_id,omitempty"`
+	SeriesID       *int64     `json:"series_id,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+	ExpiresAt      *time.Time `json:"expires_at,omitempty"`
+}
+
+type ExportedComment struct {
+	ID        int64     `json:"id"`
+	PostID    int64     `json:"post_id"`
+	Content   string    `json:"content"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// ExportedRelation is another account linked to the user by a follow,
+// follow request, block or mute, and when the link was made.
+type ExportedRelation struct {
+	UserID    int64     `json:"user_id"`
+	Username  string    `json:"username"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type ExportedPollVote struct {
+	PollID    int64     `json:"poll_id"`
+	PostID    int64     `json:"post_id"`
+	OptionIDs []int64   `json:"option_ids"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type ExportedAvatar struct {
+	ContentType string
+	Data        []byte
+}
+
+type ExportStore struct {
+	db *sql.DB
+}
+
+// Create queues an export for userID. A user with an export still pending or
+// processing gets ErrConflict.
+func (s *ExportStore) Create(ctx context.Context, userID int64) (*DataExport, error) {
+	query := `
+		INSERT INTO data_exports (user_id) VALUES ($1)
+		RETURNING id, status, created_at
+	`
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	export := &DataExport{UserID: userID}
+	if err := s.db.QueryRowContext(ctx, query, userID).Scan(&export.ID, &export.Status, &export.CreatedAt); err != nil {
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+			return nil, ErrConflict
+		}
+		return nil, err
+	}
+	return export, nil
+}
+
+// GetByID returns one of userID's exports.
+func (s *ExportStore) GetByID(ctx context.Context, id, userID int64) (*DataExport, error) {
+	query := `
+		SELECT id, user_id, status, created_at, completed_at, expires_at
+		FROM data_exports WHERE id = $1 AND user_id = $2
+	`
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	export := &DataExport{}
+	err := s.db.QueryRowContext(ctx, query, id, userID).Scan(
+		&export.ID,
+		&export.UserID,
+		&export.Status,
+		&export.CreatedAt,
+		&export.CompletedAt,
+		&export.ExpiresAt,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrNotFound
+		default:
+			return nil, err
+		}
+	}
+	return export, nil
+}
+
+// Claim hands the oldest pending export to the caller, marking it
+// processing. It gives ErrNotFound when there is nothing to do.
+func (s *ExportStore) Claim(ctx context.Context) (*DataExport, error) {
+	query := `
+		UPDATE data_exports SET status = 'processing', started_at = NOW()
+		WHERE id = (
+			SELECT id FROM data_exports
+			WHERE status = 'pending' OR
+				(status = 'processing' AND started_at < NOW() - make_interval(secs => $1))
+			ORDER BY created_at
+			LIMIT 1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING id, user_id, status, created_at
+	`
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	export := &DataExport{}
+	err := s.db.QueryRowContext(ctx, query, exportStaleAfter.Seconds()).Scan(&export.ID, &export.UserID, &export.Status, &export.CreatedAt)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrNotFound
+		default:
+			return nil, err
+		}
+	}
+	return export, nil
+}
+
+// Complete stores the archive of an export and makes it downloadable until
+// expiresAt.
+func (s *ExportStore) Complete(ctx context.Context, export *DataExport, archive []byte, expiresAt time.Time) error {
+	query := `
+		UPDATE data_exports SET status = 'ready', archive = $1, completed_at = NOW(), expires_at = $2
+		WHERE id = $3
+		RETURNING status, completed_at, expires_at
+	`
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	return s.db.QueryRowContext(ctx, query, archive, expiresAt, export.ID).Scan(&export.Status, &export.CompletedAt, &export.ExpiresAt)
+}
+
+func (s *ExportStore) Fail(ctx context.Context, id int64) error {
+	query := `UPDATE data_exports SET status = 'failed', completed_at = NOW() WHERE id = $1`
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	_, err := s.db.ExecContext(ctx, query, id)
+	return err
+}
+
+// GetArchive returns the archive of a ready export that has not expired.
+func (s *ExportStore) GetArchive(ctx context.Context, id int64) ([]byte, error) {
+	query := `
+		SELECT archive FROM data_exports
+		WHERE id = $1 AND status = 'ready' AND expires_at > NOW()
+	`
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	var archive []byte
+	if err := s.db.QueryRowContext(ctx, query, id).Scan(&archive); err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrNotFound
+		default:
+			return nil, err
+		}
+	}
+	return archive, nil
+}
+
+// DeleteExpired drops the archives of exports past their expiry.
+func (s *ExportStore) DeleteExpired(ctx context.Context) error {
+	query := `
+		UPDATE data_exports SET status = 'expired', archive = NULL
+		WHERE status = 'ready' AND expires_at <= NOW()
+	`
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	_, err := s.db.ExecContext(ctx, query)
+	return err
+}
+
+// Collect gathers the data held about userID for an export. Accounts
+// pending deletion are collected too. Every query gets its own timeout, so
+// large accounts are not cut short.
+func (s *ExportStore) Collect(ctx context.Context, userID int64) (*UserData, error) {
+	profile, err := s.profile(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	settings, err := (&SettingsStore{s.db}).Get(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	data := &UserData{
+		Profile:         profile,
+		Settings:        settings,
+		UsernameHistory: []ExportedUsername{},
+		Posts:           []ExportedPost{},
+		Comments:        []ExportedComment{},
+		PollVotes:       []ExportedPollVote{},
+	}
+
+	err = s.each(ctx, `SELECT username, changed_at FROM username_history WHERE user_id = $1 ORDER BY changed_at`, userID, func(rows *sql.Rows) error {
+		var u ExportedUsername
+		if err := rows.Scan(&u.Username, &u.ChangedAt); err != nil {
+			return err
+		}
+		data.UsernameHistory = append(data.UsernameHistory, u)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	query := `
+		SELECT id, title, content, tags, visibility, content_warning, sensitive, version,
+			thread_id, series_id, created_at, updated_at, expires_at
+		FROM posts WHERE user_id = $1 ORDER BY created_at
+	`
+	err = s.each(ctx, query, userID, func(rows *sql.Rows) error {
+		var p ExportedPost
+		err := rows.Scan(&p.ID, &p.Title, &p.Content, pq.Array(&p.Tags), &p.Visibility, &p.ContentWarning, &p.Sensitive, &p.Version,
+			&p.ThreadID, &p.SeriesID, &p.CreatedAt, &p.UpdatedAt, &p.ExpiresAt)
+		if err != nil {
+			return err
+		}
+		data.Posts = append(data.Posts, p)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.each(ctx, `SELECT id, post_id, content, created_at FROM comments WHERE user_id = $1 ORDER BY created_at`, userID, func(rows *sql.Rows) error {
+		var c ExportedComment
+		if err := rows.Scan(&c.ID, &c.PostID, &c.Content, &c.CreatedAt); err != nil {
+			return err
+		}
+		data.Comments = append(data.Comments, c)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	relations := []struct {
+		dest                      *[]ExportedRelation
+		table, ownerCol, otherCol string
+	}{
+		{&data.Followers, "followers", "user_id", "follower_id"},
+		{&data.Following, "followers", "follower_id", "user_id"},
+		{&data.RequestsSent, "follow_requests", "requester_id", "user_id"},
+		{&data.RequestsReceived, "follow_requests", "user_id", "requester_id"},
+		{&data.Blocks, "blocks", "blocker_id", "blocked_id"},
+		{&data.Mutes, "mutes", "muter_id", "muted_id"},
+	}
+	for _, rel := range relations {
+		list := []ExportedRelation{}
+		query := `
+			SELECT u.id, u.username, x.created_at
+			FROM ` + rel.table + ` x
+			JOIN users u ON u.id = x.` + rel.otherCol + `
+			WHERE x.` + rel.ownerCol + ` = $1
+			ORDER BY x.created_at
+		`
+		err := s.each(ctx, query, userID, func(rows *sql.Rows) error {
+			var r ExportedRelation
+			if err := rows.Scan(&r.UserID, &r.Username, &r.CreatedAt); err != nil {
+				return err
+			}
+			list = append(list, r)
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		*rel.dest = list
+	}
+
+	query = `
+		SELECT v.poll_id, p.post_id, v.option_ids, v.created_at
+		FROM poll_votes v
+		JOIN polls p ON p.id = v.poll_id
+		WHERE v.user_id = $1
+		ORDER BY v.created_at
+	`
+	err = s.each(ctx, query, userID, func(rows *sql.Rows) error {
+		var v ExportedPollVote
+		if err := rows.Scan(&v.PollID, &v.PostID, pq.Array(&v.OptionIDs), &v.CreatedAt); err != nil {
+			return err
+		}
+		data.PollVotes = append(data.PollVotes, v)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	avatarCtx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	avatar := &ExportedAvatar{}
+	err = s.db.QueryRowContext(avatarCtx, `SELECT content_type, data FROM user_avatars WHERE user_id = $1`, userID).Scan(&avatar.ContentType, &avatar.Data)
+	switch {
+	case err == nil:
+		data.Avatar = avatar
+	case !errors.Is(err, sql.ErrNoRows):
+		return nil, err
+	}
+
+	return data, nil
+}
+
+// profile loads the account of userID whether or not it is active, unlike
+// UserStore.GetByID.
+func (s *ExportStore) profile(ctx context.Context, userID int64) (*User, error) {
+	query := `
+		SELECT users.id, username, email, created_at, is_active,
+			display_name, bio, location, links, avatar_url, followers_count, following_count,
+			is_private, username_changed_at, deletion_requested_at, roles.*
+		FROM users
+		JOIN roles ON (users.role_id=roles.id)
+		WHERE users.id = $1
+	`
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	user := &User{}
+	err := s.db.QueryRowContext(ctx, query, userID).Scan(
+		&user.ID,
+		&user.Username,
+		&user.Email,
+		&user.CreatedAt,
+		&user.IsActive,
+		&user.DisplayName,
+		&user.Bio,
+		&user.Location,
+		pq.Array(&user.Links),
+		&user.AvatarURL,
+		&user.FollowersCount,
+		&user.FollowingCount,
+		&user.IsPrivate,
+		&user.UsernameChangedAt,
+		&user.DeletionRequestedAt,
+		&user.Role.Id,
+		&user.Role.Name,
+		&user.Role.Level,
+		&user.Role.Description,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrNotFound
+		default:
+			return nil, err
+		}
+	}
+	return user, nil
+}
+
+// each runs query for userID with its own timeout and hands every row to
+// fn.
+func (s *ExportStore) each(ctx context.Context, query string, userID int64, fn func(*sql.Rows) error) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		if err := fn(rows); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
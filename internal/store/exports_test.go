@@ -0,0 +1,29 @@
+package store
+
+import (
+	"context"
+	"testing"
+)
+
+func TestCollectAccountPendingDeletion(t *testing.T) {
+	db := newTestDB(t)
+	ctx := context.Background()
+
+	user := createTestUser(t, db, "leaving")
+	post := createTestPost(t, db, user.ID, VisibilityPrivate)
+
+	if _, err := (&UserStore{db}).RequestDeletion(ctx, user.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := (&ExportStore{db}).Collect(ctx, user.ID)
+	if err != nil {
+		t.Fatalf("Collect: %v", err)
+	}
+	if data.Profile.ID != user.ID || data.Profile.DeletionRequestedAt == nil {
+		t.Errorf("profile = %+v, want user %d pending deletion", data.Profile, user.ID)
+	}
+	if len(data.Posts) != 1 || data.Posts[0].ID != post.ID {
+		t.Errorf("posts = %+v, want post %d", data.Posts, post.ID)
+	}
+}
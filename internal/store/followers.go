@@ -3,6 +3,7 @@ package store
 import (
 	"context"
 	"database/sql"
+	"time"
 
 	"github.com/lib/pq"
 )
@@ -17,31 +18,130 @@ type FollowerStore struct {
 	db *sql.DB
 }
 
-func (s *FollowerStore) Follow(ctx context.Context, followedID, userID int64) error {
+// FollowListEntry is an account in a followers or following list, flagged
+// with how it relates to the viewer.
+type FollowListEntry struct {
+	ID          int64     `json:"id"`
+	Username    string    `json:"username"`
+	DisplayName string    `json:"display_name"`
+	AvatarURL   string    `json:"avatar_url"`
+	FollowsYou  bool      `json:"follows_you"`
+	YouFollow   bool      `json:"you_follow"`
+	FollowedAt  time.Time `json:"followed_at"`
+}
+
+// Follow makes followerID follow userID. Following twice gives ErrConflict
+// and following an unknown user ErrNotFound.
+func (s *FollowerStore) Follow(ctx context.Context, followerID, userID int64) error {
 	query := `
 	INSERT INTO followers (user_id,follower_id) VALUES ($1,$2);
 	`
 	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
 	defer cancel()
 
-	_, err := s.db.ExecContext(ctx, query, userID, followedID)
+	_, err := s.db.ExecContext(ctx, query, userID, followerID)
 	if err != nil {
 		if pqErr, ok := err.(*pq.Error); ok {
-			if pqErr.Code == "23505" {
+			switch pqErr.Code {
+			case "23505":
 				return ErrConflict
+			case "23503":
+				return ErrNotFound
 			}
 		}
+		return err
 	}
 	return nil
 }
-func (s *FollowerStore) UnFollow(ctx context.Context, followedID, userID int64) error {
+func (s *FollowerStore) UnFollow(ctx context.Context, followerID, userID int64) error {
 	query := `
 	DELETE FROM followers WHERE user_id=$1 AND follower_id=$2;
 	`
 	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
 	defer cancel()
 
-	_, err := s.db.ExecContext(ctx, query, userID, followedID)
+	_, err := s.db.ExecContext(ctx, query, userID, followerID)
 
 	return err
 }
+
+// GetFollowers lists the accounts following userID, most recent first.
+func (s *FollowerStore) GetFollowers(ctx context.Context, userID, viewerID int64, cursor string, limit int) ([]FollowListEntry, string, error) {
+	return s.list(ctx, "f.user_id", "f.follower_id", userID, viewerID, cursor, limit)
+}
+
+// GetFollowing lists the accounts userID follows, most recently followed
+// first.
+func (s *FollowerStore) GetFollowing(ctx context.Context, userID, viewerID int64, cursor string, limit int) ([]FollowListEntry, string, error) {
+	return s.list(ctx, "f.follower_id", "f.user_id", userID, viewerID, cursor, limit)
+}
+
+// list pages through the followers rows whose ownerCol is userID, returning
+// the accounts in otherCol. It also returns the cursor of the next page,
+// empty on the last one.
+func (s *FollowerStore) list(ctx context.Context, ownerCol, otherCol string, userID, viewerID int64, cursor string, limit int) ([]FollowListEntry, string, error) {
+	var cursorAt *time.Time
+	var cursorID *int64
+	if cursor != "" {
+		c, err := DecodeCursor(cursor)
+		if err != nil {
+			return nil, "", err
+		}
+		cursorAt, cursorID = &c.CreatedAt, &c.ID
+	}
+
+	query := `
+		SELECT u.id, u.username, u.display_name, u.avatar_url, f.created_at,
+			EXISTS (SELECT 1 FROM followers x WHERE x.user_id = $2 AND x.follower_id = u.id) AS follows_you,
+			EXISTS (SELECT 1 FROM followers x WHERE x.user_id = u.id AND x.follower_id = $2) AS you_follow
+		FROM followers f
+		JOIN users u ON u.id = ` + otherCol + `
+		WHERE ` + ownerCol + ` = $1 AND u.is_active = true AND
+			NOT ` + blockedBetween("$2", "u.id") + ` AND
+			($3::timestamptz IS NULL OR (f.created_at, ` + otherCol + `) < ($3, $4::bigint))
+		ORDER BY f.created_at DESC, ` + otherCol + ` DESC
+		LIMIT $5
+	`
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, userID, viewerID, cursorAt, cursorID, limit+1)
+	if err != nil {
+		return nil, "", err
+	}
+	defer rows.Close()
+
+	entries := []FollowListEntry{}
+	for rows.Next() {
+		var e FollowListEntry
+		err := rows.Scan(&e.ID, &e.Username, &e.DisplayName, &e.AvatarURL, &e.FollowedAt, &e.FollowsYou, &e.YouFollow)
+		if err != nil {
+			return nil, "", err
+		}
+		entries = append(entries, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, "", err
+	}
+
+	if len(entries) <= limit {
+		return entries, "", nil
+	}
+
+	entries = entries[:limit]
+	last := entries[len(entries)-1]
+	return entries, Cursor{CreatedAt: last.FollowedAt, ID: last.ID}.Encode(), nil
+}
+
+// IsFollowing reports whether followerID follows userID.
+func (s *FollowerStore) IsFollowing(ctx context.Context, followerID, userID int64) (bool, error) {
+	query := `
+	SELECT EXISTS (SELECT 1 FROM followers WHERE user_id=$1 AND follower_id=$2);
+	`
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	var following bool
+	err := s.db.QueryRowContext(ctx, query, userID, followerID).Scan(&following)
+	return following, err
+}
@@ -0,0 +1,182 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+type FollowRequest struct {
+	ID          int64     `json:"id"`
+	Username    string    `json:"username"`
+	DisplayName string    `json:"display_name"`
+	AvatarURL   string    `json:"avatar_url"`
+	RequestedAt time.Time `json:"requested_at"`
+}
+
+type FollowRequestStore struct {
+	db *sql.DB
+}
+
+// Create records that requesterID asks to follow userID. Asking twice, or
+// while already following, gives ErrConflict.
+func (s *FollowRequestStore) Create(ctx context.Context, requesterID, userID int64) error {
+	query := `
+		INSERT INTO follow_requests (user_id, requester_id)
+		SELECT $1, $2
+		WHERE NOT EXISTS (SELECT 1 FROM followers WHERE user_id = $1 AND follower_id = $2)
+	`
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	result, err := s.db.ExecContext(ctx, query, userID, requesterID)
+	if err != nil {
+		if pqErr, ok := err.(*pq.Error); ok {
+			switch pqErr.Code {
+			case "23505":
+				return ErrConflict
+			case "23503":
+				return ErrNotFound
+			}
+		}
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrConflict
+	}
+	return nil
+}
+
+// GetIncoming lists the pending requests to follow userID, most recent
+// first, with the cursor of the next page.
+func (s *FollowRequestStore) GetIncoming(ctx context.Context, userID int64, cursor string, limit int) ([]FollowRequest, string, error) {
+	var cursorAt *time.Time
+	var cursorID *int64
+	if cursor != "" {
+		c, err := DecodeCursor(cursor)
+		if err != nil {
+			return nil, "", err
+		}
+		cursorAt, cursorID = &c.CreatedAt, &c.ID
+	}
+
+	query := `
+		SELECT u.id, u.username, u.display_name, u.avatar_url, fr.created_at
+		FROM follow_requests fr
+		JOIN users u ON u.id = fr.requester_id
+		WHERE fr.user_id = $1 AND
+			($2::timestamptz IS NULL OR (fr.created_at, fr.requester_id) < ($2, $3::bigint))
+		ORDER BY fr.created_at DESC, fr.requester_id DESC
+		LIMIT $4
+	`
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, userID, cursorAt, cursorID, limit+1)
+	if err != nil {
+		return nil, "", err
+	}
+	defer rows.Close()
+
+	requests := []FollowRequest{}
+	for rows.Next() {
+		var fr FollowRequest
+		if err := rows.Scan(&fr.ID, &fr.Username, &fr.DisplayName, &fr.AvatarURL, &fr.RequestedAt); err != nil {
+			return nil, "", err
+		}
+		requests = append(requests, fr)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, "", err
+	}
+
+	if len(requests) <= limit {
+		return requests, "", nil
+	}
+
+	requests = requests[:limit]
+	last := requests[len(requests)-1]
+	return requests, Cursor{CreatedAt: last.RequestedAt, ID: last.ID}.Encode(), nil
+}
+
+// Approve turns a pending request into a follow.
+func (s *FollowRequestStore) Approve(ctx context.Context, userID, requesterID int64) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	return withTx(s.db, ctx, func(tx *sql.Tx) error {
+		if err := deleteFollowRequest(ctx, tx, userID, requesterID); err != nil {
+			return err
+		}
+
+		query := `
+			INSERT INTO followers (user_id, follower_id) VALUES ($1, $2)
+			ON CONFLICT DO NOTHING
+		`
+		_, err := tx.ExecContext(ctx, query, userID, requesterID)
+		return err
+	})
+}
+
+// Delete drops a pending request, whether rejected by userID or withdrawn
+// by requesterID.
+func (s *FollowRequestStore) Delete(ctx context.Context, userID, requesterID int64) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	return deleteFollowRequest(ctx, s.db, userID, requesterID)
+}
+
+// approveAllFollowRequests accepts every pending request to follow userID,
+// used when the account stops being private, and returns the requesters.
+func approveAllFollowRequests(ctx context.Context, tx *sql.Tx, userID int64) ([]int64, error) {
+	query := `
+		WITH approved AS (
+			DELETE FROM follow_requests WHERE user_id = $1 RETURNING requester_id
+		)
+		INSERT INTO followers (user_id, follower_id)
+		SELECT $1, requester_id FROM approved
+		ON CONFLICT DO NOTHING
+		RETURNING follower_id
+	`
+	rows, err := tx.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ids := []int64{}
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+func deleteFollowRequest(ctx context.Context, q queryer, userID, requesterID int64) error {
+	query := `DELETE FROM follow_requests WHERE user_id = $1 AND requester_id = $2`
+
+	result, err := q.ExecContext(ctx, query, userID, requesterID)
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
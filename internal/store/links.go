@@ -0,0 +1,145 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// maxPostLinks bounds how many URLs of a post get a preview.
+const maxPostLinks = 3
+
+type LinkPreview struct {
+	URL         string `json:"url"`
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	ImageURL    string `json:"image_url"`
+	SiteName    string `json:"site_name"`
+}
+
+type LinkPreviewStore struct {
+	db *sql.DB
+}
+
+// syncLinks replaces the URLs a post links to and queues the ones never
+// seen before for unfurling.
+func syncLinks(ctx context.Context, tx *sql.Tx, postID int64, urls []string) error {
+	if len(urls) > maxPostLinks {
+		urls = urls[:maxPostLinks]
+	}
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM post_links WHERE post_id = $1`, postID); err != nil {
+		return err
+	}
+	if len(urls) == 0 {
+		return nil
+	}
+
+	query := `
+		INSERT INTO link_previews (url)
+		SELECT unnest($1::text[])
+		ON CONFLICT (url) DO NOTHING
+	`
+	if _, err := tx.ExecContext(ctx, query, pq.Array(urls)); err != nil {
+		return err
+	}
+
+	query = `
+		INSERT INTO post_links (post_id, url, position)
+		SELECT $1, u.url, u.n FROM unnest($2::text[]) WITH ORDINALITY AS u(url, n)
+	`
+	_, err := tx.ExecContext(ctx, query, postID, pq.Array(urls))
+	return err
+}
+
+// Due lists URLs still linked from a post that were never fetched or whose
+// cached preview is older than ttl.
+func (s *LinkPreviewStore) Due(ctx context.Context, ttl time.Duration, limit int) ([]string, error) {
+	query := `
+		SELECT lp.url FROM link_previews lp
+		WHERE (lp.status = 'pending' OR lp.fetched_at < $1) AND
+			EXISTS (SELECT 1 FROM post_links pl WHERE pl.url = lp.url)
+		ORDER BY lp.fetched_at NULLS FIRST, lp.created_at
+		LIMIT $2
+	`
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, time.Now().Add(-ttl), limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	urls := []string{}
+	for rows.Next() {
+		var url string
+		if err := rows.Scan(&url); err != nil {
+			return nil, err
+		}
+		urls = append(urls, url)
+	}
+	return urls, rows.Err()
+}
+
+// Save caches the preview of a URL. A nil preview records a failed fetch,
+// which is retried once the cache entry expires.
+func (s *LinkPreviewStore) Save(ctx context.Context, url string, preview *LinkPreview) error {
+	query := `
+		UPDATE link_previews
+		SET status = 'failed', fetched_at = NOW()
+		WHERE url = $1
+	`
+	args := []any{url}
+	if preview != nil {
+		query = `
+			UPDATE link_previews
+			SET status = 'ok', title = $2, description = $3, image_url = $4, site_name = $5, fetched_at = NOW()
+			WHERE url = $1
+		`
+		args = append(args, preview.Title, preview.Description, preview.ImageURL, preview.SiteName)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	_, err := s.db.ExecContext(ctx, query, args...)
+	return err
+}
+
+// GetByPostIDs returns the fetched previews of the given posts keyed by post
+// ID, in the order the links appear in each post.
+func (s *LinkPreviewStore) GetByPostIDs(ctx context.Context, postIDs []int64) (map[int64][]LinkPreview, error) {
+	previews := map[int64][]LinkPreview{}
+	if len(postIDs) == 0 {
+		return previews, nil
+	}
+
+	query := `
+		SELECT pl.post_id, lp.url, lp.title, lp.description, lp.image_url, lp.site_name
+		FROM post_links pl
+		JOIN link_previews lp ON lp.url = pl.url
+		WHERE pl.post_id = ANY($1) AND lp.status = 'ok'
+		ORDER BY pl.post_id, pl.position
+	`
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, pq.Array(postIDs))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var postID int64
+		var lp LinkPreview
+		if err := rows.Scan(&postID, &lp.URL, &lp.Title, &lp.Description, &lp.ImageURL, &lp.SiteName); err != nil {
+			return nil, err
+		}
+		previews[postID] = append(previews[postID], lp)
+	}
+	return previews, rows.Err()
+}
@@ -0,0 +1,53 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/lib/pq"
+)
+
+// syncMentions replaces the mentions and hashtags recorded for a post, or
+// for a single comment on it when commentID is set. Usernames that do not
+// belong to an account, or whose account blocks the author or is blocked by
+// them, are ignored.
+func syncMentions(ctx context.Context, tx *sql.Tx, postID int64, commentID *int64, usernames, tags []string) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	queries := []string{
+		`DELETE FROM mentions WHERE post_id = $1 AND comment_id IS NOT DISTINCT FROM $2`,
+		`DELETE FROM hashtags WHERE post_id = $1 AND comment_id IS NOT DISTINCT FROM $2`,
+	}
+	for _, query := range queries {
+		if _, err := tx.ExecContext(ctx, query, postID, commentID); err != nil {
+			return err
+		}
+	}
+
+	if len(usernames) > 0 {
+		query := `
+			INSERT INTO mentions (post_id, comment_id, user_id)
+			SELECT $1::bigint, $2::bigint, u.id FROM users u
+			WHERE u.username = ANY($3) AND NOT ` + blockedBetween("u.id", `COALESCE(
+				(SELECT user_id FROM comments WHERE id = $2::bigint),
+				(SELECT user_id FROM posts WHERE id = $1::bigint)
+			)`) + `
+		`
+		if _, err := tx.ExecContext(ctx, query, postID, commentID, pq.Array(usernames)); err != nil {
+			return err
+		}
+	}
+
+	if len(tags) > 0 {
+		query := `
+			INSERT INTO hashtags (post_id, comment_id, tag)
+			SELECT DISTINCT $1::bigint, $2::bigint, t FROM unnest($3::varchar[]) AS t
+		`
+		if _, err := tx.ExecContext(ctx, query, postID, commentID, pq.Array(tags)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
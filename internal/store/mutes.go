@@ -0,0 +1,56 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/lib/pq"
+)
+
+type MuteStore struct {
+	db *sql.DB
+}
+
+// Mute hides mutedID's posts from muterID's feed. Unlike a block it is not
+// visible to the muted user and leaves follows alone.
+func (s *MuteStore) Mute(ctx context.Context, muterID, mutedID int64) error {
+	query := `
+		INSERT INTO mutes (muter_id, muted_id) VALUES ($1, $2)
+		ON CONFLICT DO NOTHING
+	`
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	if _, err := s.db.ExecContext(ctx, query, muterID, mutedID); err != nil {
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23503" {
+			return ErrNotFound
+		}
+		return err
+	}
+	return nil
+}
+
+func (s *MuteStore) Unmute(ctx context.Context, muterID, mutedID int64) error {
+	query := `DELETE FROM mutes WHERE muter_id = $1 AND muted_id = $2`
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	result, err := s.db.ExecContext(ctx, query, muterID, mutedID)
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// GetMuted lists the accounts userID mutes, most recent first.
+func (s *MuteStore) GetMuted(ctx context.Context, userID int64, cursor string, limit int) ([]BlockListEntry, string, error) {
+	return listBlockEntries(ctx, s.db, "mutes", "muter_id", "muted_id", userID, cursor, limit)
+}
@@ -1,12 +1,16 @@
 package store
 
 import (
+	"encoding/base64"
+	"errors"
 	"net/http"
 	"strconv"
 	"strings"
 	"time"
 )
 
+var ErrInvalidCursor = errors.New("invalid cursor")
+
 type PaginatedFeedQuery struct {
 	Limit  int        `json:"limit" validate:"gte=1,lte=20"`
 	Offset int        `json:"offset" validate:"gte=0"`
@@ -15,6 +19,45 @@ type PaginatedFeedQuery struct {
 	Search string     `json:"search" validate:"max=100"`
 	Since  *time.Time `json:"since"`
 	Until  *time.Time `json:"until"`
+	Cursor string     `json:"cursor" validate:"max=200"`
+}
+
+// Filtered reports whether the query narrows results by tags or search.
+func (fq PaginatedFeedQuery) Filtered() bool {
+	return len(fq.Tags) > 0 || fq.Search != ""
+}
+
+// Cursor marks the position of the last row of a page for keyset
+// pagination on (created_at, id).
+type Cursor struct {
+	CreatedAt time.Time
+	ID        int64
+}
+
+func (c Cursor) Encode() string {
+	raw := c.CreatedAt.Format(time.RFC3339Nano) + "|" + strconv.FormatInt(c.ID, 10)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func DecodeCursor(s string) (*Cursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	createdAt, id, ok := strings.Cut(string(raw), "|")
+	if !ok {
+		return nil, ErrInvalidCursor
+	}
+
+	c := &Cursor{}
+	if c.CreatedAt, err = time.Parse(time.RFC3339Nano, createdAt); err != nil {
+		return nil, ErrInvalidCursor
+	}
+	if c.ID, err = strconv.ParseInt(id, 10, 64); err != nil {
+		return nil, ErrInvalidCursor
+	}
+	return c, nil
 }
 
 func (fq PaginatedFeedQuery) Parse(r *http.Request) (PaginatedFeedQuery, error) {
@@ -57,6 +100,9 @@ func (fq PaginatedFeedQuery) Parse(r *http.Request) (PaginatedFeedQuery, error)
 		}
 		fq.Until = &t
 	}
+	if cursor := qs.Get("cursor"); cursor != "" {
+		fq.Cursor = cursor
+	}
 
 	return fq, nil
 }
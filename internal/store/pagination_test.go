@@ -0,0 +1,27 @@
+package store
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestCursorRoundTrip(t *testing.T) {
+	want := Cursor{CreatedAt: time.Date(2024, 3, 1, 12, 30, 0, 123456789, time.UTC), ID: 42}
+
+	got, err := DecodeCursor(want.Encode())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !got.CreatedAt.Equal(want.CreatedAt) || got.ID != want.ID {
+		t.Errorf("DecodeCursor(Encode()) = %+v, want %+v", *got, want)
+	}
+}
+
+func TestDecodeCursorRejectsGarbage(t *testing.T) {
+	for _, s := range []string{"!!!", "bm9waXBl", "MjAyNC0wMy0wMVQxMjozMDowMFp8eA"} {
+		if _, err := DecodeCursor(s); !errors.Is(err, ErrInvalidCursor) {
+			t.Errorf("DecodeCursor(%q): got %v, want ErrInvalidCursor", s, err)
+		}
+	}
+}
@@ -0,0 +1,100 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/lib/pq"
+)
+
+var ErrPinLimit = errors.New("pinned posts limit reached")
+
+type PinStore struct {
+	db *sql.DB
+}
+
+// Pin adds the post at the end of the user's pinned posts, refusing once the
+// user already has limit pins.
+func (s *PinStore) Pin(ctx context.Context, userID, postID int64, limit int) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	return withTx(s.db, ctx, func(tx *sql.Tx) error {
+		// Serialise concurrent pins by the same user so the limit holds.
+		if _, err := tx.ExecContext(ctx, `SELECT id FROM users WHERE id = $1 FOR UPDATE`, userID); err != nil {
+			return err
+		}
+
+		var count, next int
+		query := `SELECT COUNT(*), COALESCE(MAX(position), 0) + 1 FROM pinned_posts WHERE user_id = $1`
+		if err := tx.QueryRowContext(ctx, query, userID).Scan(&count, &next); err != nil {
+			return err
+		}
+		if count >= limit {
+			return ErrPinLimit
+		}
+
+		query = `INSERT INTO pinned_posts (user_id, post_id, position) VALUES ($1, $2, $3)`
+		if _, err := tx.ExecContext(ctx, query, userID, postID, next); err != nil {
+			if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+				return ErrConflict
+			}
+			return err
+		}
+		return nil
+	})
+}
+
+func (s *PinStore) Unpin(ctx context.Context, userID, postID int64) error {
+	query := `DELETE FROM pinned_posts WHERE user_id = $1 AND post_id = $2`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	result, err := s.db.ExecContext(ctx, query, userID, postID)
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// GetByUserID lists a user's pinned posts in pin order.
+func (s *PinStore) GetByUserID(ctx context.Context, userID, viewerID int64) ([]PostWithMetadata, error) {
+	query := `
+		SELECT ` + postListColumns + `
+		FROM pinned_posts pp
+		JOIN posts p ON p.id = pp.post_id
+		JOIN users u ON u.id = p.user_id
+		WHERE pp.user_id = $1 AND ` + visiblePostsClause(2) + `
+		ORDER BY pp.position
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, userID, viewerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanPostsWithMetadata(rows)
+}
+
+func unpinIfNotPublic(ctx context.Context, tx *sql.Tx, post *Post) error {
+	if post.Visibility == VisibilityPublic {
+		return nil
+	}
+
+	_, err := tx.ExecContext(ctx, `DELETE FROM pinned_posts WHERE post_id = $1`, post.ID)
+	return err
+}
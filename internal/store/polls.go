@@ -0,0 +1,233 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+var (
+	ErrPollClosed  = errors.New("poll is closed")
+	ErrInvalidVote = errors.New("invalid poll options")
+)
+
+type Poll struct {
+	ID             int64        `json:"id"`
+	PostID         int64        `json:"post_id"`
+	MultipleChoice bool         `json:"multiple_choice"`
+	HideResults    bool         `json:"hide_results"`
+	ClosesAt       time.Time    `json:"closes_at"`
+	Closed         bool         `json:"closed"`
+	Options        []PollOption `json:"options"`
+	TotalVotes     *int         `json:"total_votes,omitempty"`
+	ViewerVote     []int64      `json:"viewer_vote"`
+}
+
+type PollOption struct {
+	ID       int64  `json:"id"`
+	Position int    `json:"position"`
+	Text     string `json:"text"`
+	Votes    *int   `json:"votes,omitempty"`
+}
+
+// ResultsVisible reports whether vote counts may be shown for the poll.
+func (p *Poll) ResultsVisible() bool {
+	return !p.HideResults || p.Closed
+}
+
+type PollStore struct {
+	db *sql.DB
+}
+
+func createPoll(ctx context.Context, tx *sql.Tx, postID int64, poll *Poll) error {
+	query := `
+		INSERT INTO polls (post_id, multiple_choice, hide_results, closes_at)
+		VALUES ($1, $2, $3, $4) RETURNING id
+	`
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	poll.PostID = postID
+	if err := tx.QueryRowContext(ctx, query, postID, poll.MultipleChoice, poll.HideResults, poll.ClosesAt).Scan(&poll.ID); err != nil {
+		return err
+	}
+
+	query = `INSERT INTO poll_options (poll_id, position, text) VALUES ($1, $2, $3) RETURNING id`
+	for i := range poll.Options {
+		opt := &poll.Options[i]
+		opt.Position = i
+		if err := tx.QueryRowContext(ctx, query, poll.ID, opt.Position, opt.Text).Scan(&opt.ID); err != nil {
+			return err
+		}
+	}
+
+	poll.Closed = time.Now().After(poll.ClosesAt)
+	poll.ViewerVote = []int64{}
+	return nil
+}
+
+func (s *PollStore) GetByPostID(ctx context.Context, postID, viewerID int64) (*Poll, error) {
+	polls, err := s.GetByPostIDs(ctx, []int64{postID}, viewerID)
+	if err != nil {
+		return nil, err
+	}
+
+	poll, ok := polls[postID]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return poll, nil
+}
+
+// GetByPostIDs loads the polls attached to the given posts keyed by post ID,
+// including the viewer's own vote. Counts are left out while results are
+// hidden.
+func (s *PollStore) GetByPostIDs(ctx context.Context, postIDs []int64, viewerID int64) (map[int64]*Poll, error) {
+	polls := map[int64]*Poll{}
+	if len(postIDs) == 0 {
+		return polls, nil
+	}
+
+	query := `
+		SELECT p.id, p.post_id, p.multiple_choice, p.hide_results, p.closes_at,
+			COALESCE(v.option_ids, '{}')
+		FROM polls p
+		LEFT JOIN poll_votes v ON v.poll_id = p.id AND v.user_id = $2
+		WHERE p.post_id = ANY($1)
+	`
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, pq.Array(postIDs), viewerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	byID := map[int64]*Poll{}
+	pollIDs := []int64{}
+	for rows.Next() {
+		p := &Poll{Options: []PollOption{}}
+		if err := rows.Scan(&p.ID, &p.PostID, &p.MultipleChoice, &p.HideResults, &p.ClosesAt, pq.Array(&p.ViewerVote)); err != nil {
+			return nil, err
+		}
+		p.Closed = time.Now().After(p.ClosesAt)
+		polls[p.PostID] = p
+		byID[p.ID] = p
+		pollIDs = append(pollIDs, p.ID)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(pollIDs) == 0 {
+		return polls, nil
+	}
+
+	query = `
+		SELECT o.poll_id, o.id, o.position, o.text, COUNT(v.user_id)
+		FROM poll_options o
+		LEFT JOIN poll_votes v ON v.poll_id = o.poll_id AND o.id = ANY(v.option_ids)
+		WHERE o.poll_id = ANY($1)
+		GROUP BY o.id
+		ORDER BY o.poll_id, o.position
+	`
+	optRows, err := s.db.QueryContext(ctx, query, pq.Array(pollIDs))
+	if err != nil {
+		return nil, err
+	}
+	defer optRows.Close()
+
+	for optRows.Next() {
+		var (
+			pollID int64
+			votes  int
+			opt    PollOption
+		)
+		if err := optRows.Scan(&pollID, &opt.ID, &opt.Position, &opt.Text, &votes); err != nil {
+			return nil, err
+		}
+		p := byID[pollID]
+		if p.ResultsVisible() {
+			opt.Votes = &votes
+		}
+		p.Options = append(p.Options, opt)
+	}
+	if err := optRows.Err(); err != nil {
+		return nil, err
+	}
+
+	query = `SELECT poll_id, COUNT(*) FROM poll_votes WHERE poll_id = ANY($1) GROUP BY poll_id`
+	totalRows, err := s.db.QueryContext(ctx, query, pq.Array(pollIDs))
+	if err != nil {
+		return nil, err
+	}
+	defer totalRows.Close()
+
+	for totalRows.Next() {
+		var pollID int64
+		var total int
+		if err := totalRows.Scan(&pollID, &total); err != nil {
+			return nil, err
+		}
+		if p := byID[pollID]; p.ResultsVisible() {
+			p.TotalVotes = &total
+		}
+	}
+	for _, p := range byID {
+		if p.TotalVotes == nil && p.ResultsVisible() {
+			zero := 0
+			p.TotalVotes = &zero
+		}
+	}
+
+	return polls, totalRows.Err()
+}
+
+// Vote records a user's single ballot on a poll. A user who already voted
+// gets ErrConflict.
+func (s *PollStore) Vote(ctx context.Context, pollID, userID int64, optionIDs []int64) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	return withTx(s.db, ctx, func(tx *sql.Tx) error {
+		var closesAt time.Time
+		var multiple bool
+		query := `SELECT closes_at, multiple_choice FROM polls WHERE id = $1 FOR SHARE`
+		if err := tx.QueryRowContext(ctx, query, pollID).Scan(&closesAt, &multiple); err != nil {
+			switch {
+			case errors.Is(err, sql.ErrNoRows):
+				return ErrNotFound
+			default:
+				return err
+			}
+		}
+
+		if time.Now().After(closesAt) {
+			return ErrPollClosed
+		}
+		if len(optionIDs) == 0 || (!multiple && len(optionIDs) > 1) {
+			return ErrInvalidVote
+		}
+
+		var valid int
+		query = `SELECT COUNT(*) FROM poll_options WHERE poll_id = $1 AND id = ANY($2)`
+		if err := tx.QueryRowContext(ctx, query, pollID, pq.Array(optionIDs)).Scan(&valid); err != nil {
+			return err
+		}
+		if valid != len(optionIDs) {
+			return ErrInvalidVote
+		}
+
+		query = `INSERT INTO poll_votes (poll_id, user_id, option_ids) VALUES ($1, $2, $3)`
+		if _, err := tx.ExecContext(ctx, query, pollID, userID, pq.Array(optionIDs)); err != nil {
+			if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+				return ErrConflict
+			}
+			return err
+		}
+		return nil
+	})
+}
@@ -4,49 +4,115 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"fmt"
 	"log"
+	"time"
 
 	"github.com/lib/pq"
 )
 
 type Post struct {
-	ID        int64     `json:"id"`
-	Content   string    `json:"content"`
-	Title     string    `json:"title"`
-	UserID    int64     `json:"user_id"`
-	Tags      []string  `json:"tags"`
-	CreatedAt string    `json:"created_at"`
-	UpdateAt  string    `json:"updated_at"`
-	Comments  []Comment `json:"comments"`
-	Version   int       `json:"version"`
-	User      User      `json:"user"`
+	ID             int64           `json:"id"`
+	Content        string          `json:"content"`
+	ContentHTML    string          `json:"content_html"`
+	Title          string          `json:"title"`
+	UserID         int64           `json:"user_id"`
+	Tags           []string        `json:"tags"`
+	ExplicitTags   []string        `json:"-"`
+	CreatedAt      string          `json:"created_at"`
+	UpdateAt       string          `json:"updated_at"`
+	Comments       []Comment       `json:"comments"`
+	Version        int             `json:"version"`
+	Visibility     string          `json:"visibility"`
+	ContentWarning string          `json:"content_warning"`
+	Sensitive      bool            `json:"sensitive"`
+	Collapsed      bool            `json:"collapsed"`
+	User           User            `json:"user"`
+	Mentions       []string        `json:"-"`
+	Links          []string        `json:"-"`
+	LinkPreviews   []LinkPreview   `json:"link_previews,omitempty"`
+	Poll           *Poll           `json:"poll,omitempty"`
+	ThreadID       *int64          `json:"thread_id,omitempty"`
+	ThreadPosition *int            `json:"thread_position,omitempty"`
+	SeriesID       *int64          `json:"series_id,omitempty"`
+	ExpiresAt      *time.Time      `json:"expires_at,omitempty"`
+	Navigation     *PostNavigation `json:"navigation,omitempty"`
 }
 
 type PostWithMetadata struct {
 	Post
 	CommentsCount int `json:"comment_count"`
+	ThreadParts   int `json:"thread_parts,omitempty"`
 }
 
+// postListColumns are the columns read by scanPostsWithMetadata, selected
+// from posts p joined with their author u.
+const postListColumns = `
+		p.id,
+		p.user_id,
+		p.title,
+		p.content,
+		p.content_html,
+		p.created_at,
+		p.version,
+		p.tags,
+		p.visibility,
+		p.content_warning,
+		p.sensitive,
+		p.thread_id,
+		p.thread_position,
+		p.series_id,
+		p.expires_at,
+		u.username,
+		(SELECT COUNT(*) FROM comments c WHERE c.post_id = p.id) AS comment_count,
+		(SELECT COUNT(*) FROM posts tp WHERE tp.thread_id = p.thread_id) AS thread_parts`
+
+// expiredDeleteBatchSize bounds how many expired posts are deleted per
+// statement.
+const expiredDeleteBatchSize = 500
+
 type PostStore struct {
 	db *sql.DB
 }
 
 func (s *PostStore) Create(ctx context.Context, post *Post) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	return withTx(s.db, ctx, func(tx *sql.Tx) error {
+		return createPost(ctx, tx, post)
+	})
+}
+
+// createPost inserts a post with its tags, mentions and poll inside tx.
+func createPost(ctx context.Context, tx *sql.Tx, post *Post) error {
 	query := `
-	INSERT INTO posts (content,title,user_id,tags)
-	VALUES($1,$2,$3,$4) RETURNING id,created_at,updated_at
+	INSERT INTO posts (content,content_html,title,user_id,tags,explicit_tags,visibility,content_warning,thread_id,thread_position,expires_at)
+	VALUES($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11) RETURNING id,created_at,updated_at
 	`
 
-	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
-	defer cancel()
+	if post.Visibility == "" {
+		post.Visibility = VisibilityPublic
+	}
 
-	if err := s.db.QueryRowContext(
+	if err := resolvePostTags(ctx, tx, post); err != nil {
+		return err
+	}
+
+	if err := tx.QueryRowContext(
 		ctx,
 		query,
 		post.Content,
+		post.ContentHTML,
 		post.Title,
 		post.UserID,
 		pq.Array(post.Tags),
+		pq.Array(post.ExplicitTags),
+		post.Visibility,
+		post.ContentWarning,
+		post.ThreadID,
+		post.ThreadPosition,
+		post.ExpiresAt,
 	).Scan(
 		&post.ID,
 		&post.CreatedAt,
@@ -54,12 +120,26 @@ func (s *PostStore) Create(ctx context.Context, post *Post) error {
 	); err != nil {
 		return err
 	}
+
+	if err := syncMentions(ctx, tx, post.ID, nil, post.Mentions, post.Tags); err != nil {
+		return err
+	}
+
+	if err := syncLinks(ctx, tx, post.ID, post.Links); err != nil {
+		return err
+	}
+
+	if post.Poll != nil {
+		return createPoll(ctx, tx, post.ID, post.Poll)
+	}
 	return nil
 }
 
 func (s *PostStore) GetByID(ctx context.Context, id int64) (*Post, error) {
 	query := `
-		SELECT id,user_id,title,content,created_at,updated_at,tags,version FROM posts WHERE id=$1
+		SELECT id,user_id,title,content,content_html,created_at,updated_at,tags,explicit_tags,version,visibility,content_warning,sensitive,thread_id,thread_position,series_id,expires_at
+		FROM posts WHERE id=$1 AND (expires_at IS NULL OR expires_at > NOW()) AND
+			NOT EXISTS (SELECT 1 FROM users u WHERE u.id = posts.user_id AND u.deletion_requested_at IS NOT NULL)
 	`
 
 	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
@@ -71,10 +151,19 @@ func (s *PostStore) GetByID(ctx context.Context, id int64) (*Post, error) {
 		&post.UserID,
 		&post.Title,
 		&post.Content,
+		&post.ContentHTML,
 		&post.CreatedAt,
 		&post.UpdateAt,
 		pq.Array(&post.Tags),
+		pq.Array(&post.ExplicitTags),
 		&post.Version,
+		&post.Visibility,
+		&post.ContentWarning,
+		&post.Sensitive,
+		&post.ThreadID,
+		&post.ThreadPosition,
+		&post.SeriesID,
+		&post.ExpiresAt,
 	)
 	if err != nil {
 		switch {
@@ -90,25 +179,50 @@ func (s *PostStore) GetByID(ctx context.Context, id int64) (*Post, error) {
 func (s *PostStore) Update(ctx context.Context, post *Post) error {
 	query := `
 		UPDATE posts
-		SET title=$1,content=$2,version=version+1
-		WHERE id=$3 AND version=$4;
+		SET title=$1,content=$2,content_html=$3,tags=$4,explicit_tags=$5,visibility=$6,content_warning=$7,version=version+1
+		WHERE id=$8 AND version=$9
 		RETURNING version
 	`
 	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
 	defer cancel()
 
-	err := s.db.QueryRowContext(ctx, query, post.Title, post.Content, post.ID, post.Version).Scan(&post.Version)
-	if err != nil {
-		switch {
-		case errors.Is(err, sql.ErrNoRows):
-			return ErrNotFound
-		default:
+	return withTx(s.db, ctx, func(tx *sql.Tx) error {
+		if err := resolvePostTags(ctx, tx, post); err != nil {
 			return err
 		}
 
-	}
+		err := tx.QueryRowContext(
+			ctx,
+			query,
+			post.Title,
+			post.Content,
+			post.ContentHTML,
+			pq.Array(post.Tags),
+			pq.Array(post.ExplicitTags),
+			post.Visibility,
+			post.ContentWarning,
+			post.ID,
+			post.Version,
+		).Scan(&post.Version)
+		if err != nil {
+			switch {
+			case errors.Is(err, sql.ErrNoRows):
+				return ErrEditConflict
+			default:
+				return err
+			}
+		}
 
-	return nil
+		// Only public posts may stay pinned to a profile.
+		if err := unpinIfNotPublic(ctx, tx, post); err != nil {
+			return err
+		}
+
+		if err := syncMentions(ctx, tx, post.ID, nil, post.Mentions, post.Tags); err != nil {
+			return err
+		}
+		return syncLinks(ctx, tx, post.ID, post.Links)
+	})
 }
 func (s *PostStore) Delete(ctx context.Context, id int64) error {
 	query := `
@@ -138,25 +252,16 @@ func (s *PostStore) Delete(ctx context.Context, id int64) error {
 
 func (s *PostStore) GetUserFeed(ctx context.Context, userID int64, fq PaginatedFeedQuery) ([]PostWithMetadata, error) {
 	query := `
-		SELECT
-		p.id, 
-		p.user_id, 
-		p.title, 
-		p.content, 
-		p.created_at, 
-		p.version, 
-		p.tags,
-		u.username,
-		COUNT(c.id) AS comment_count
+		SELECT ` + postListColumns + `
 		FROM posts p
-		LEFT JOIN comments c ON c.post_id=p.id
-		LEFT JOIN users u ON p.user_id=u.id
-		JOIN followers f ON f.follower_id = p.user_id OR p.user_id=$1
-		WHERE 
-			f.user_id = $1 AND 
-			(p.title ILIKE '%' || $4 || '%' OR p.content ILIKE '%' || $4 || '%') AND
-			(p.tags @> $5 OR $5 = '{}')
-		GROUP BY p.id, u.username
+		JOIN users u ON p.user_id=u.id
+		WHERE ` + feedPostsClause("p") + ` AND
+			-- A thread shows up once, as its first part the feed would show.
+			(p.thread_id IS NULL OR NOT EXISTS (
+				SELECT 1 FROM posts tp
+				WHERE tp.thread_id = p.thread_id AND tp.thread_position < p.thread_position AND
+					` + feedPostsClause("tp") + `
+			))
 		ORDER BY p.created_at ` + fq.Sort + `
 		LIMIT $2 OFFSET $3;
 	`
@@ -168,7 +273,12 @@ func (s *PostStore) GetUserFeed(ctx context.Context, userID int64, fq PaginatedF
 	log.Printf("Executing query: %s", query)
 	log.Printf("Parameters: userID=%v, Limit=%v, Offset=%v, Search=%v, Tags=%v", userID, fq.Limit, fq.Offset, fq.Search, fq.Tags)
 
-	rows, err := s.db.QueryContext(ctx, query, userID, fq.Limit, fq.Offset, fq.Search, pq.Array(fq.Tags))
+	tags, err := canonicalTags(ctx, s.db, fq.Tags)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := s.db.QueryContext(ctx, query, userID, fq.Limit, fq.Offset, fq.Search, pq.Array(tags))
 	if err != nil {
 		log.Printf("Query error: %v", err)
 		return nil, err
@@ -176,31 +286,315 @@ func (s *PostStore) GetUserFeed(ctx context.Context, userID int64, fq PaginatedF
 
 	defer rows.Close()
 
-	var feed []PostWithMetadata
-	for rows.Next() {
-		var p PostWithMetadata
-		err := rows.Scan(
-			&p.ID,
-			&p.UserID,
-			&p.Title,
-			&p.Content,
-			&p.CreatedAt,
-			&p.Version,
-			pq.Array(&p.Tags),
-			&p.User.Username,
-			&p.CommentsCount,
+	feed, err := scanPostsWithMetadata(rows)
+	if err != nil {
+		log.Printf("Rows error: %v", err)
+		return nil, err
+	}
+
+	return feed, nil
+}
+
+// feedPostsClause restricts the posts aliased a to the ones the feed of
+// the user at $1 shows, with the search at $4 and tags at $5.
+func feedPostsClause(a string) string {
+	return fmt.Sprintf(`(
+			(%[1]s.user_id = $1 OR EXISTS (
+				SELECT 1 FROM followers f WHERE f.user_id = %[1]s.user_id AND f.follower_id = $1
+			)) AND
+			%[2]s AND
+			(%[1]s.title ILIKE '%%' || $4 || '%%' OR %[1]s.content ILIKE '%%' || $4 || '%%') AND
+			(%[1]s.tags @> $5 OR $5 = '{}') AND
+			(%[1]s.user_id = $1 OR NOT (%[1]s.sensitive OR %[1]s.content_warning <> '') OR NOT EXISTS (
+				SELECT 1 FROM user_settings us WHERE us.user_id = $1 AND us.settings->>'sensitive_content' = 'hide'
+			)) AND
+			NOT EXISTS (SELECT 1 FROM mutes mu WHERE mu.muter_id = $1 AND mu.muted_id = %[1]s.user_id)
+		)`, a, visiblePostsClauseFor(a, 1))
+}
+
+// GetByUserID lists the posts of one user visible to the viewer, newest
+// first by default, using keyset pagination on (created_at, id). Pinned
+// posts are left out of unfiltered listings since they are shown on top of
+// the profile separately. It also returns the cursor of the next page, empty
+// on the last one.
+func (s *PostStore) GetByUserID(ctx context.Context, userID, viewerID int64, fq PaginatedFeedQuery) ([]PostWithMetadata, string, error) {
+	var cursorAt *time.Time
+	var cursorID *int64
+	if fq.Cursor != "" {
+		c, err := DecodeCursor(fq.Cursor)
+		if err != nil {
+			return nil, "", err
+		}
+		cursorAt, cursorID = &c.CreatedAt, &c.ID
+	}
+
+	keyset := "<"
+	if fq.Sort == "asc" {
+		keyset = ">"
+	}
+
+	query := `
+		SELECT ` + postListColumns + `
+		FROM posts p
+		JOIN users u ON u.id = p.user_id
+		WHERE p.user_id = $1 AND
+			` + visiblePostsClause(2) + ` AND
+			(p.title ILIKE '%' || $3 || '%' OR p.content ILIKE '%' || $3 || '%') AND
+			(p.tags @> $4 OR $4 = '{}') AND
+			($5::timestamptz IS NULL OR p.created_at >= $5) AND
+			($6::timestamptz IS NULL OR p.created_at <= $6) AND
+			($7::timestamptz IS NULL OR (p.created_at, p.id) ` + keyset + ` ($7, $8::bigint)) AND
+			($9 OR NOT EXISTS (SELECT 1 FROM pinned_posts pp WHERE pp.post_id = p.id))
+		ORDER BY p.created_at ` + fq.Sort + `, p.id ` + fq.Sort + `
+		LIMIT $10
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	tags, err := canonicalTags(ctx, s.db, fq.Tags)
+	if err != nil {
+		return nil, "", err
+	}
+
+	rows, err := s.db.QueryContext(
+		ctx,
+		query,
+		userID,
+		viewerID,
+		fq.Search,
+		pq.Array(tags),
+		fq.Since,
+		fq.Until,
+		cursorAt,
+		cursorID,
+		fq.Filtered(),
+		fq.Limit+1,
+	)
+	if err != nil {
+		return nil, "", err
+	}
+	defer rows.Close()
+
+	posts, err := scanPostsWithMetadata(rows)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if len(posts) <= fq.Limit {
+		return posts, "", nil
+	}
+
+	posts = posts[:fq.Limit]
+	last := posts[len(posts)-1]
+	createdAt, err := time.Parse(time.RFC3339Nano, last.CreatedAt)
+	if err != nil {
+		return nil, "", err
+	}
+	return posts, Cursor{CreatedAt: createdAt, ID: last.ID}.Encode(), nil
+}
+
+// GetByMention lists the posts whose content mentions the given user and
+// that the viewer may see.
+func (s *PostStore) GetByMention(ctx context.Context, userID, viewerID int64, fq PaginatedFeedQuery) ([]PostWithMetadata, error) {
+	query := `
+		SELECT ` + postListColumns + `
+		FROM posts p
+		JOIN users u ON u.id = p.user_id
+		WHERE EXISTS (
+			SELECT 1 FROM mentions m
+			WHERE m.post_id = p.id AND m.comment_id IS NULL AND m.user_id = $1
+		) AND ` + visiblePostsClause(4) + `
+		ORDER BY p.created_at ` + fq.Sort + `
+		LIMIT $2 OFFSET $3
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, userID, fq.Limit, fq.Offset, viewerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanPostsWithMetadata(rows)
+}
+
+// GetByHashtag lists the posts tagged with tag, either explicitly or through
+// a #hashtag in their content, that the viewer may see.
+func (s *PostStore) GetByHashtag(ctx context.Context, tag string, viewerID int64, fq PaginatedFeedQuery) ([]PostWithMetadata, error) {
+	query := `
+		SELECT ` + postListColumns + `
+		FROM posts p
+		JOIN users u ON u.id = p.user_id
+		WHERE EXISTS (
+			SELECT 1 FROM hashtags h
+			WHERE h.post_id = p.id AND h.comment_id IS NULL AND h.tag = $1
+		) AND ` + visiblePostsClause(4) + `
+		ORDER BY p.created_at ` + fq.Sort + `
+		LIMIT $2 OFFSET $3
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	tags, err := canonicalTags(ctx, s.db, []string{tag})
+	if err != nil || len(tags) == 0 {
+		return []PostWithMetadata{}, err
+	}
+
+	rows, err := s.db.QueryContext(ctx, query, tags[0], fq.Limit, fq.Offset, viewerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanPostsWithMetadata(rows)
+}
+
+// SetSensitive flags or unflags a post as sensitive media.
+func (s *PostStore) SetSensitive(ctx context.Context, postID int64, sensitive bool) error {
+	query := `UPDATE posts SET sensitive = $1 WHERE id = $2`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	result, err := s.db.ExecContext(ctx, query, sensitive, postID)
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// SetExpiry sets or, with a nil expiresAt, removes the time at which a post
+// is deleted.
+func (s *PostStore) SetExpiry(ctx context.Context, postID int64, expiresAt *time.Time) error {
+	query := `
+		UPDATE posts SET expires_at = $1
+		WHERE id = $2 AND (expires_at IS NULL OR expires_at > NOW())
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	result, err := s.db.ExecContext(ctx, query, expiresAt, postID)
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// DeleteExpired removes expired posts in batches. Their comments, polls and
+// other attachments go with them through their foreign keys.
+func (s *PostStore) DeleteExpired(ctx context.Context) error {
+	query := `
+		DELETE FROM posts WHERE id IN (
+			SELECT id FROM posts WHERE expires_at <= NOW() LIMIT $1
 		)
+	`
+
+	for {
+		batchCtx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+		result, err := s.db.ExecContext(batchCtx, query, expiredDeleteBatchSize)
+		cancel()
 		if err != nil {
-			log.Printf("Row scan error: %v", err)
+			return err
+		}
+
+		rows, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if rows < expiredDeleteBatchSize {
+			return nil
+		}
+	}
+}
+
+// IsSensitive reports whether the post carries a content warning or was
+// flagged as sensitive.
+func (p *Post) IsSensitive() bool {
+	return p.Sensitive || p.ContentWarning != ""
+}
+
+// Collapse turns the post into a placeholder that keeps its title and
+// content warning but drops the body.
+func (p *Post) Collapse() {
+	p.Content = ""
+	p.ContentHTML = ""
+	p.Poll = nil
+	p.Collapsed = true
+}
+
+func scanPostsWithMetadata(rows *sql.Rows) ([]PostWithMetadata, error) {
+	posts := []PostWithMetadata{}
+	for rows.Next() {
+		var p PostWithMetadata
+		if err := rows.Scan(postListDest(&p)...); err != nil {
 			return nil, err
 		}
-		feed = append(feed, p)
+		p.User.ID = p.UserID
+		posts = append(posts, p)
 	}
 
-	if err = rows.Err(); err != nil {
-		log.Printf("Rows error: %v", err)
+	if err := rows.Err(); err != nil {
 		return nil, err
 	}
+	return posts, nil
+}
 
-	return feed, nil
+// postListDest returns the scan destinations matching postListColumns.
+func postListDest(p *PostWithMetadata) []any {
+	return []any{
+		&p.ID,
+		&p.UserID,
+		&p.Title,
+		&p.Content,
+		&p.ContentHTML,
+		&p.CreatedAt,
+		&p.Version,
+		pq.Array(&p.Tags),
+		&p.Visibility,
+		&p.ContentWarning,
+		&p.Sensitive,
+		&p.ThreadID,
+		&p.ThreadPosition,
+		&p.SeriesID,
+		&p.ExpiresAt,
+		&p.User.Username,
+		&p.CommentsCount,
+		&p.ThreadParts,
+	}
+}
+
+// resolvePostTags canonicalises the tags of post and the explicitly given
+// subset of them.
+func resolvePostTags(ctx context.Context, tx *sql.Tx, post *Post) error {
+	tags, err := resolveTags(ctx, tx, post.Tags)
+	if err != nil {
+		return err
+	}
+	explicit, err := canonicalTags(ctx, tx, post.ExplicitTags)
+	if err != nil {
+		return err
+	}
+	post.Tags, post.ExplicitTags = tags, explicit
+	return nil
 }
@@ -0,0 +1,208 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"slices"
+	"testing"
+)
+
+// userPostIDs pages through the posts of userID as seen by viewerID with
+// pages of limit posts, calling between after each page.
+func userPostIDs(t *testing.T, db *sql.DB, userID, viewerID int64, fq PaginatedFeedQuery, between func()) []int64 {
+	t.Helper()
+
+	ids := []int64{}
+	for {
+		posts, next, err := (&PostStore{db}).GetByUserID(context.Background(), userID, viewerID, fq)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(posts) > fq.Limit {
+			t.Fatalf("got a page of %d posts, want at most %d", len(posts), fq.Limit)
+		}
+		for _, p := range posts {
+			ids = append(ids, p.ID)
+		}
+		if next == "" {
+			return ids
+		}
+		fq.Cursor = next
+		if between != nil {
+			between()
+		}
+	}
+}
+
+func TestGetByUserIDPagination(t *testing.T) {
+	db := newTestDB(t)
+	author := createTestUser(t, db, "author")
+
+	// Posts created within the same second share created_at, so the order
+	// and the cursor rely on the id tie-breaker.
+	var want []int64
+	for range 5 {
+		want = append(want, createTestPost(t, db, author.ID, VisibilityPublic).ID)
+	}
+	slices.Reverse(want)
+
+	fq := PaginatedFeedQuery{Limit: 2, Sort: "desc"}
+
+	var added []int64
+	got := userPostIDs(t, db, author.ID, author.ID, fq, func() {
+		// Posts added while paging must neither shift nor repeat rows.
+		added = append(added, createTestPost(t, db, author.ID, VisibilityPublic).ID)
+	})
+	if !slices.Equal(got, want) {
+		t.Errorf("pages = %v, want %v", got, want)
+	}
+
+	all := append(slices.Clone(added), want...)
+	slices.Sort(all)
+	fq.Sort = "asc"
+	if got := userPostIDs(t, db, author.ID, author.ID, fq, nil); !slices.Equal(got, all) {
+		t.Errorf("ascending pages = %v, want %v", got, all)
+	}
+
+	fq.Cursor = "not-a-cursor"
+	if _, _, err := (&PostStore{db}).GetByUserID(context.Background(), author.ID, author.ID, fq); !errors.Is(err, ErrInvalidCursor) {
+		t.Errorf("invalid cursor: got %v, want ErrInvalidCursor", err)
+	}
+}
+
+func TestGetByUserIDVisibility(t *testing.T) {
+	db := newTestDB(t)
+	author := createTestUser(t, db, "author")
+	follower := createTestUser(t, db, "follower")
+	stranger := createTestUser(t, db, "stranger")
+
+	if err := (&FollowerStore{db}).Follow(context.Background(), follower.ID, author.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	ids := map[string]int64{}
+	for _, visibility := range visibilities {
+		ids[visibility] = createTestPost(t, db, author.ID, visibility).ID
+	}
+
+	tests := []struct {
+		viewer   string
+		viewerID int64
+		want     []string
+	}{
+		{"author", author.ID, []string{VisibilityPrivate, VisibilityUnlisted, VisibilityFollowers, VisibilityPublic}},
+		{"follower", follower.ID, []string{VisibilityFollowers, VisibilityPublic}},
+		{"non-follower", stranger.ID, []string{VisibilityPublic}},
+		{"anonymous", 0, []string{VisibilityPublic}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.viewer, func(t *testing.T) {
+			want := []int64{}
+			for _, visibility := range tt.want {
+				want = append(want, ids[visibility])
+			}
+
+			got := userPostIDs(t, db, author.ID, tt.viewerID, PaginatedFeedQuery{Limit: 20, Sort: "desc"}, nil)
+			if !slices.Equal(got, want) {
+				t.Errorf("posts = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestGetByUserIDPinnedPosts(t *testing.T) {
+	db := newTestDB(t)
+	ctx := context.Background()
+	author := createTestUser(t, db, "author")
+
+	var posts []int64
+	for range 4 {
+		posts = append(posts, createTestPost(t, db, author.ID, VisibilityPublic).ID)
+	}
+
+	pins := &PinStore{db}
+	for _, id := range []int64{posts[2], posts[0]} {
+		if err := pins.Pin(ctx, author.ID, id, 3); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Pinned posts head the profile through Pins.GetByUserID, in pin order,
+	// and are left out of the plain listing.
+	pinned, err := pins.GetByUserID(ctx, author.ID, author.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var pinnedIDs []int64
+	for _, p := range pinned {
+		pinnedIDs = append(pinnedIDs, p.ID)
+	}
+	if want := []int64{posts[2], posts[0]}; !slices.Equal(pinnedIDs, want) {
+		t.Errorf("pinned = %v, want %v", pinnedIDs, want)
+	}
+
+	got := userPostIDs(t, db, author.ID, author.ID, PaginatedFeedQuery{Limit: 20, Sort: "desc"}, nil)
+	if want := []int64{posts[3], posts[1]}; !slices.Equal(got, want) {
+		t.Errorf("unfiltered posts = %v, want %v", got, want)
+	}
+
+	// A search lists every match, pinned or not.
+	got = userPostIDs(t, db, author.ID, author.ID, PaginatedFeedQuery{Limit: 20, Sort: "desc", Search: "Test"}, nil)
+	if want := []int64{posts[3], posts[2], posts[1], posts[0]}; !slices.Equal(got, want) {
+		t.Errorf("searched posts = %v, want %v", got, want)
+	}
+}
+
+func TestGetUserFeedCollapsesThreadsToFirstVisiblePart(t *testing.T) {
+	db := newTestDB(t)
+	ctx := context.Background()
+	author := createTestUser(t, db, "author")
+	follower := createTestUser(t, db, "follower")
+
+	if err := (&FollowerStore{db}).Follow(ctx, follower.ID, author.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	var parts []*Post
+	for i := range 3 {
+		parts = append(parts, &Post{Title: fmt.Sprintf("Part %d", i+1), Content: "Thread content", Visibility: VisibilityPublic})
+	}
+	if _, err := (&ThreadStore{db}).Create(ctx, author.ID, parts); err != nil {
+		t.Fatal(err)
+	}
+
+	feedPart := func() int64 {
+		t.Helper()
+
+		feed, err := (&PostStore{db}).GetUserFeed(ctx, follower.ID, PaginatedFeedQuery{Limit: 20, Sort: "desc"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(feed) != 1 {
+			t.Fatalf("feed has %d posts, want the thread once", len(feed))
+		}
+		return feed[0].ID
+	}
+
+	if got := feedPart(); got != parts[0].ID {
+		t.Errorf("feed shows post %d, want the first part %d", got, parts[0].ID)
+	}
+
+	// Parts the follower can no longer see do not hide the rest.
+	if _, err := db.Exec(`UPDATE posts SET expires_at = NOW() - interval '1 minute' WHERE id = $1`, parts[0].ID); err != nil {
+		t.Fatal(err)
+	}
+	if got := feedPart(); got != parts[1].ID {
+		t.Errorf("with the first part expired, feed shows post %d, want %d", got, parts[1].ID)
+	}
+
+	if _, err := db.Exec(`UPDATE posts SET visibility = 'private' WHERE id = $1`, parts[1].ID); err != nil {
+		t.Fatal(err)
+	}
+	if got := feedPart(); got != parts[2].ID {
+		t.Errorf("with the second part private, feed shows post %d, want %d", got, parts[2].ID)
+	}
+}
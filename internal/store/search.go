@@ -0,0 +1,73 @@
+package store
+
+import (
+	"context"
+	"html"
+	"strings"
+)
+
+const (
+	// searchRecencyDays is the age in days at which a post's relevance is
+	// halved when ranking search results.
+	searchRecencyDays = 30
+
+	// Sentinels marking matches in snippets. They are swapped for <mark>
+	// tags once the rest of the snippet has been escaped.
+	snippetStartSel = "[[[mark]]]"
+	snippetStopSel  = "[[[/mark]]]"
+)
+
+type PostSearchResult struct {
+	PostWithMetadata
+	Rank    float64 `json:"rank"`
+	Snippet string  `json:"snippet"`
+}
+
+// Search finds the posts visible to the viewer matching a web search style
+// query, which supports "quoted phrases", OR and -exclusions. Results are
+// ordered by text relevance, with title matches weighing more than content,
+// damped by the age of the post.
+func (s *PostStore) Search(ctx context.Context, q string, viewerID int64, limit, offset int) ([]PostSearchResult, error) {
+	query := `
+		SELECT ` + postListColumns + `,
+			ts_rank_cd(p.search_vector, sq.query, 32) /
+				(1 + EXTRACT(EPOCH FROM NOW() - p.created_at) / 86400 / $5) AS rank,
+			ts_headline('english', p.content, sq.query,
+				'StartSel="` + snippetStartSel + `", StopSel="` + snippetStopSel + `", MaxWords=35, MinWords=15, MaxFragments=2')
+		FROM posts p
+		JOIN users u ON u.id = p.user_id
+		CROSS JOIN websearch_to_tsquery('english', $1) AS sq(query)
+		WHERE p.search_vector @@ sq.query AND ` + visiblePostsClause(2) + `
+		ORDER BY rank DESC, p.id DESC
+		LIMIT $3 OFFSET $4
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, q, viewerID, limit, offset, searchRecencyDays)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	results := []PostSearchResult{}
+	for rows.Next() {
+		var r PostSearchResult
+		if err := rows.Scan(append(postListDest(&r.PostWithMetadata), &r.Rank, &r.Snippet)...); err != nil {
+			return nil, err
+		}
+		r.User.ID = r.UserID
+		r.Snippet = highlightSnippet(r.Snippet)
+		results = append(results, r)
+	}
+	return results, rows.Err()
+}
+
+// highlightSnippet escapes a ts_headline snippet of raw content and wraps
+// the matched words in <mark> tags.
+func highlightSnippet(snippet string) string {
+	snippet = html.EscapeString(snippet)
+	snippet = strings.ReplaceAll(snippet, html.EscapeString(snippetStartSel), "<mark>")
+	return strings.ReplaceAll(snippet, html.EscapeString(snippetStopSel), "</mark>")
+}
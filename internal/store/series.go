@@ -0,0 +1,146 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+)
+
+type Series struct {
+	ID          int64         `json:"id"`
+	UserID      int64         `json:"user_id"`
+	Title       string        `json:"title"`
+	Description string        `json:"description"`
+	CreatedAt   string        `json:"created_at"`
+	Contents    []SeriesEntry `json:"contents"`
+}
+
+// SeriesEntry is a line of a series' table of contents.
+type SeriesEntry struct {
+	PostID    int64  `json:"post_id"`
+	Title     string `json:"title"`
+	Position  int    `json:"position"`
+	CreatedAt string `json:"created_at"`
+}
+
+type SeriesStore struct {
+	db *sql.DB
+}
+
+func (s *SeriesStore) Create(ctx context.Context, series *Series) error {
+	query := `
+		INSERT INTO series (user_id, title, description)
+		VALUES ($1, $2, $3) RETURNING id, created_at
+	`
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	series.Contents = []SeriesEntry{}
+	return s.db.QueryRowContext(ctx, query, series.UserID, series.Title, series.Description).Scan(
+		&series.ID,
+		&series.CreatedAt,
+	)
+}
+
+// GetByID returns a series with a table of contents of the posts the viewer
+// may see, in series order.
+func (s *SeriesStore) GetByID(ctx context.Context, id, viewerID int64) (*Series, error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	series := &Series{Contents: []SeriesEntry{}}
+	query := `SELECT id, user_id, title, description, created_at FROM series WHERE id = $1`
+	err := s.db.QueryRowContext(ctx, query, id).Scan(
+		&series.ID,
+		&series.UserID,
+		&series.Title,
+		&series.Description,
+		&series.CreatedAt,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	query = `
+		SELECT p.id, p.title, ROW_NUMBER() OVER (ORDER BY p.series_position), p.created_at
+		FROM posts p
+		WHERE p.series_id = $1 AND ` + visiblePostsClause(2) + `
+		ORDER BY p.series_position
+	`
+	rows, err := s.db.QueryContext(ctx, query, id, viewerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var e SeriesEntry
+		if err := rows.Scan(&e.PostID, &e.Title, &e.Position, &e.CreatedAt); err != nil {
+			return nil, err
+		}
+		series.Contents = append(series.Contents, e)
+	}
+	return series, rows.Err()
+}
+
+// AddPost appends a post to the end of a series. A post already in a series
+// gives ErrConflict.
+func (s *SeriesStore) AddPost(ctx context.Context, seriesID, postID int64) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	return withTx(s.db, ctx, func(tx *sql.Tx) error {
+		// Serialise concurrent additions so positions stay unique.
+		if _, err := tx.ExecContext(ctx, `SELECT id FROM series WHERE id = $1 FOR UPDATE`, seriesID); err != nil {
+			return err
+		}
+
+		query := `
+			UPDATE posts SET series_id = $1, series_position = (
+				SELECT COALESCE(MAX(series_position), 0) + 1 FROM posts WHERE series_id = $1
+			)
+			WHERE id = $2 AND series_id IS NULL
+		`
+		result, err := tx.ExecContext(ctx, query, seriesID, postID)
+		if err != nil {
+			return err
+		}
+
+		rows, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if rows == 0 {
+			return ErrConflict
+		}
+		return nil
+	})
+}
+
+func (s *SeriesStore) RemovePost(ctx context.Context, seriesID, postID int64) error {
+	query := `
+		UPDATE posts SET series_id = NULL, series_position = NULL
+		WHERE id = $1 AND series_id = $2
+	`
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	result, err := s.db.ExecContext(ctx, query, postID, seriesID)
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
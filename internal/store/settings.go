@@ -0,0 +1,123 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// Settings is a user's preferences document. Fields missing from the stored
+// document take their value from DefaultSettings, so new settings can be
+// added without migrating existing rows.
+type Settings struct {
+	DefaultVisibility  string             `json:"default_visibility" validate:"oneof=public followers unlisted private"`
+	FeedSort           string             `json:"feed_sort" validate:"oneof=asc desc"`
+	Locale             string             `json:"locale" validate:"bcp47_language_tag"`
+	SensitiveContent   string             `json:"sensitive_content" validate:"oneof=expand collapse hide"`
+	EmailNotifications EmailNotifications `json:"email_notifications"`
+}
+
+type EmailNotifications struct {
+	Mentions       bool `json:"mentions"`
+	Comments       bool `json:"comments"`
+	NewFollowers   bool `json:"new_followers"`
+	FollowRequests bool `json:"follow_requests"`
+	ProductUpdates bool `json:"product_updates"`
+}
+
+// DefaultSettings returns the settings of a user who never changed any.
+func DefaultSettings() Settings {
+	return Settings{
+		DefaultVisibility: VisibilityPublic,
+		FeedSort:          "desc",
+		Locale:            "en",
+		SensitiveContent:  SensitiveCollapse,
+		EmailNotifications: EmailNotifications{
+			Mentions:       true,
+			Comments:       true,
+			NewFollowers:   true,
+			FollowRequests: true,
+		},
+	}
+}
+
+// UserSettings is the stored settings document of a user. Version starts at
+// 0 for a user without a stored document and is bumped on every update.
+type UserSettings struct {
+	Settings
+	Version   int        `json:"version"`
+	UpdatedAt *time.Time `json:"updated_at,omitempty"`
+}
+
+type SettingsStore struct {
+	db *sql.DB
+}
+
+// Get returns the settings of userID, falling back to the defaults.
+func (s *SettingsStore) Get(ctx context.Context, userID int64) (*UserSettings, error) {
+	query := `SELECT settings, version, updated_at FROM user_settings WHERE user_id = $1`
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	settings := &UserSettings{Settings: DefaultSettings()}
+
+	var doc []byte
+	err := s.db.QueryRowContext(ctx, query, userID).Scan(&doc, &settings.Version, &settings.UpdatedAt)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return settings, nil
+		default:
+			return nil, err
+		}
+	}
+
+	if err := json.Unmarshal(doc, &settings.Settings); err != nil {
+		return nil, err
+	}
+	return settings, nil
+}
+
+// Update stores the settings of userID if they are still at
+// settings.Version, bumping the version. A concurrent update gives
+// ErrEditConflict.
+func (s *SettingsStore) Update(ctx context.Context, userID int64, settings *UserSettings) error {
+	doc, err := json.Marshal(settings.Settings)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		UPDATE user_settings SET settings = $1, version = version + 1, updated_at = NOW()
+		WHERE user_id = $2 AND version = $3
+		RETURNING version, updated_at
+	`
+	if settings.Version == 0 {
+		query = `
+			INSERT INTO user_settings (user_id, settings) VALUES ($2, $1)
+			ON CONFLICT (user_id) DO NOTHING
+			RETURNING version, updated_at
+		`
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	args := []any{doc, userID}
+	if settings.Version != 0 {
+		args = append(args, settings.Version)
+	}
+
+	err = s.db.QueryRowContext(ctx, query, args...).Scan(&settings.Version, &settings.UpdatedAt)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrEditConflict
+		default:
+			return err
+		}
+	}
+	return nil
+}
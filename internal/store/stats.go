@@ -0,0 +1,119 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// statsFlushBatchSize bounds how many rows a single upsert writes.
+const statsFlushBatchSize = 1000
+
+// PostStatsDelta is an increment of the counters of a post on one day.
+type PostStatsDelta struct {
+	PostID      int64
+	Day         time.Time
+	Views       int64
+	Impressions int64
+}
+
+type PostStatsDay struct {
+	Day         string `json:"day"`
+	Views       int64  `json:"views"`
+	Impressions int64  `json:"impressions"`
+}
+
+type PostAnalytics struct {
+	PostID      int64          `json:"post_id"`
+	Title       string         `json:"title"`
+	Views       int64          `json:"views"`
+	Impressions int64          `json:"impressions"`
+	Daily       []PostStatsDay `json:"daily"`
+}
+
+type StatsStore struct {
+	db *sql.DB
+}
+
+// AddPostStats adds the deltas to the daily counters. Deltas of posts deleted
+// in the meantime are dropped.
+func (s *StatsStore) AddPostStats(ctx context.Context, deltas []PostStatsDelta) error {
+	query := `
+		INSERT INTO post_stats_daily (post_id, day, views, impressions)
+		SELECT d.post_id, d.day, d.views, d.impressions
+		FROM unnest($1::bigint[], $2::date[], $3::bigint[], $4::bigint[]) AS d(post_id, day, views, impressions)
+		WHERE EXISTS (SELECT 1 FROM posts p WHERE p.id = d.post_id)
+		ON CONFLICT (post_id, day) DO UPDATE SET
+			views = post_stats_daily.views + EXCLUDED.views,
+			impressions = post_stats_daily.impressions + EXCLUDED.impressions
+	`
+
+	for start := 0; start < len(deltas); start += statsFlushBatchSize {
+		batch := deltas[start:min(start+statsFlushBatchSize, len(deltas))]
+
+		postIDs := make([]int64, len(batch))
+		days := make([]string, len(batch))
+		views := make([]int64, len(batch))
+		impressions := make([]int64, len(batch))
+		for i, d := range batch {
+			postIDs[i] = d.PostID
+			days[i] = d.Day.Format(time.DateOnly)
+			views[i] = d.Views
+			impressions[i] = d.Impressions
+		}
+
+		batchCtx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+		_, err := s.db.ExecContext(batchCtx, query, pq.Array(postIDs), pq.Array(days), pq.Array(views), pq.Array(impressions))
+		cancel()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetAuthorAnalytics returns the daily counters since the given day of every
+// post of the author that was seen in that range, newest post first.
+func (s *StatsStore) GetAuthorAnalytics(ctx context.Context, userID int64, since time.Time) ([]PostAnalytics, error) {
+	query := `
+		SELECT p.id, p.title, s.day, s.views, s.impressions
+		FROM post_stats_daily s
+		JOIN posts p ON p.id = s.post_id
+		WHERE p.user_id = $1 AND s.day >= $2
+		ORDER BY p.created_at DESC, p.id DESC, s.day
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, userID, since.Format(time.DateOnly))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	posts := []PostAnalytics{}
+	for rows.Next() {
+		var (
+			postID int64
+			title  string
+			day    time.Time
+			stats  PostStatsDay
+		)
+		if err := rows.Scan(&postID, &title, &day, &stats.Views, &stats.Impressions); err != nil {
+			return nil, err
+		}
+		stats.Day = day.Format(time.DateOnly)
+
+		if len(posts) == 0 || posts[len(posts)-1].PostID != postID {
+			posts = append(posts, PostAnalytics{PostID: postID, Title: title, Daily: []PostStatsDay{}})
+		}
+		p := &posts[len(posts)-1]
+		p.Views += stats.Views
+		p.Impressions += stats.Impressions
+		p.Daily = append(p.Daily, stats)
+	}
+	return posts, rows.Err()
+}
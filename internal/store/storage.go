@@ -13,6 +13,8 @@ var (
 	ErrConflict          = errors.New("resource already exists")
 	ErrDuplicateEmail    = errors.New("email already exists")
 	ErrDuplicateUsername = errors.New("username already exists")
+	ErrUsernameCooldown  = errors.New("username was changed too recently")
+	ErrEditConflict      = errors.New("resource was modified concurrently")
 )
 
 type Storage struct {
@@ -22,6 +24,14 @@ type Storage struct {
 		Update(context.Context, *Post) error
 		Delete(context.Context, int64) error
 		GetUserFeed(context.Context, int64, PaginatedFeedQuery) ([]PostWithMetadata, error)
+		GetByUserID(ctx context.Context, userID, viewerID int64, fq PaginatedFeedQuery) ([]PostWithMetadata, string, error)
+		Search(ctx context.Context, q string, viewerID int64, limit, offset int) ([]PostSearchResult, error)
+		GetNavigation(ctx context.Context, post *Post, viewerID int64) (*PostNavigation, error)
+		SetExpiry(ctx context.Context, postID int64, expiresAt *time.Time) error
+		DeleteExpired(context.Context) error
+		GetByMention(ctx context.Context, userID, viewerID int64, fq PaginatedFeedQuery) ([]PostWithMetadata, error)
+		GetByHashtag(ctx context.Context, tag string, viewerID int64, fq PaginatedFeedQuery) ([]PostWithMetadata, error)
+		SetSensitive(ctx context.Context, postID int64, sensitive bool) error
 	}
 	Users interface {
 		GetByID(context.Context, int64) (*User, error)
@@ -30,27 +40,127 @@ type Storage struct {
 		Activate(context.Context, string) error
 		Delete(context.Context, int64) error
 		GetByEmail(context.Context, string) (*User, error)
+		UpdateProfile(context.Context, *User) error
+		SetAvatar(ctx context.Context, userID int64, contentType string, data []byte, avatarURL string) error
+		GetAvatar(ctx context.Context, userID int64) (string, []byte, error)
+		GetByUsername(context.Context, string) (*User, error)
+		ResolveFormerUsername(context.Context, string) (int64, error)
+		ChangeUsername(ctx context.Context, userID int64, username string, cooldown, grace time.Duration) error
+		Search(ctx context.Context, q string, viewerID int64, limit int) ([]UserSummary, error)
+		SetPrivate(ctx context.Context, userID int64, private bool) ([]int64, error)
+		RequestDeletion(ctx context.Context, userID int64) (time.Time, error)
+		Restore(ctx context.Context, userID int64, grace time.Duration) error
+		PurgeDeleted(ctx context.Context, grace time.Duration) error
 	}
 	Comments interface {
-		GetByPostID(context.Context, int64) ([]Comment, error)
+		GetByPostID(ctx context.Context, postID, viewerID int64) ([]Comment, error)
 		Create(context.Context, *Comment) error
 	}
 	Followers interface {
-		Follow(ctx context.Context, followedID, userID int64) error
-		UnFollow(ctx context.Context, followedID, userID int64) error
+		Follow(ctx context.Context, followerID, userID int64) error
+		UnFollow(ctx context.Context, followerID, userID int64) error
+		IsFollowing(ctx context.Context, followerID, userID int64) (bool, error)
+		GetFollowers(ctx context.Context, userID, viewerID int64, cursor string, limit int) ([]FollowListEntry, string, error)
+		GetFollowing(ctx context.Context, userID, viewerID int64, cursor string, limit int) ([]FollowListEntry, string, error)
+	}
+	FollowRequests interface {
+		Create(ctx context.Context, requesterID, userID int64) error
+		GetIncoming(ctx context.Context, userID int64, cursor string, limit int) ([]FollowRequest, string, error)
+		Approve(ctx context.Context, userID, requesterID int64) error
+		Delete(ctx context.Context, userID, requesterID int64) error
+	}
+	Blocks interface {
+		Block(ctx context.Context, blockerID, blockedID int64) error
+		Unblock(ctx context.Context, blockerID, blockedID int64) error
+		IsBlocked(ctx context.Context, userID, otherID int64) (bool, error)
+		GetBlocked(ctx context.Context, userID int64, cursor string, limit int) ([]BlockListEntry, string, error)
+	}
+	Mutes interface {
+		Mute(ctx context.Context, muterID, mutedID int64) error
+		Unmute(ctx context.Context, muterID, mutedID int64) error
+		GetMuted(ctx context.Context, userID int64, cursor string, limit int) ([]BlockListEntry, string, error)
 	}
 	Roles interface {
 		GetByName(context.Context, string) (*Role, error)
 	}
+	Polls interface {
+		GetByPostID(ctx context.Context, postID, viewerID int64) (*Poll, error)
+		GetByPostIDs(ctx context.Context, postIDs []int64, viewerID int64) (map[int64]*Poll, error)
+		Vote(ctx context.Context, pollID, userID int64, optionIDs []int64) error
+	}
+	Pins interface {
+		Pin(ctx context.Context, userID, postID int64, limit int) error
+		Unpin(ctx context.Context, userID, postID int64) error
+		GetByUserID(ctx context.Context, userID, viewerID int64) ([]PostWithMetadata, error)
+	}
+	Tags interface {
+		GetBySlug(context.Context, string) (*Tag, error)
+		Rename(ctx context.Context, slug, name string) (*Tag, error)
+		Merge(ctx context.Context, fromSlug, intoSlug string) (*Tag, error)
+		RefreshTrending(context.Context) error
+		Trending(ctx context.Context, window string, limit int) ([]TrendingTag, error)
+		Autocomplete(ctx context.Context, q string, viewerID int64, limit int) ([]Tag, error)
+	}
+	Threads interface {
+		Create(ctx context.Context, userID int64, parts []*Post) (*Thread, error)
+		GetByID(ctx context.Context, id, viewerID int64) (*Thread, error)
+	}
+	Series interface {
+		Create(context.Context, *Series) error
+		GetByID(ctx context.Context, id, viewerID int64) (*Series, error)
+		AddPost(ctx context.Context, seriesID, postID int64) error
+		RemovePost(ctx context.Context, seriesID, postID int64) error
+	}
+	LinkPreviews interface {
+		Due(ctx context.Context, ttl time.Duration, limit int) ([]string, error)
+		Save(ctx context.Context, url string, preview *LinkPreview) error
+		GetByPostIDs(context.Context, []int64) (map[int64][]LinkPreview, error)
+	}
+	Exports interface {
+		Create(ctx context.Context, userID int64) (*DataExport, error)
+		GetByID(ctx context.Context, id, userID int64) (*DataExport, error)
+		Claim(context.Context) (*DataExport, error)
+		Complete(ctx context.Context, export *DataExport, archive []byte, expiresAt time.Time) error
+		Fail(ctx context.Context, id int64) error
+		GetArchive(ctx context.Context, id int64) ([]byte, error)
+		DeleteExpired(context.Context) error
+		Collect(ctx context.Context, userID int64) (*UserData, error)
+	}
+	Settings interface {
+		Get(context.Context, int64) (*UserSettings, error)
+		Update(context.Context, int64, *UserSettings) error
+	}
+	Suggestions interface {
+		Refresh(context.Context) error
+		Get(ctx context.Context, userID int64, limit int) ([]Suggestion, error)
+		Dismiss(ctx context.Context, userID, dismissedID int64) error
+	}
+	Stats interface {
+		AddPostStats(context.Context, []PostStatsDelta) error
+		GetAuthorAnalytics(ctx context.Context, userID int64, since time.Time) ([]PostAnalytics, error)
+	}
 }
 
 func NewStorage(db *sql.DB) Storage {
 	return Storage{
-		Posts:     &PostStore{db},
-		Users:     &UserStore{db},
-		Comments:  &CommentStore{db},
-		Followers: &FollowerStore{db},
-		Roles:     &RoleStore{db},
+		Posts:          &PostStore{db},
+		Users:          &UserStore{db},
+		Comments:       &CommentStore{db},
+		Followers:      &FollowerStore{db},
+		FollowRequests: &FollowRequestStore{db},
+		Blocks:         &BlockStore{db},
+		Mutes:          &MuteStore{db},
+		Exports:        &ExportStore{db},
+		Settings:       &SettingsStore{db},
+		Suggestions:    &SuggestionStore{db},
+		Roles:          &RoleStore{db},
+		Polls:          &PollStore{db},
+		Pins:           &PinStore{db},
+		Tags:           &TagStore{db},
+		Threads:        &ThreadStore{db},
+		Series:         &SeriesStore{db},
+		Stats:          &StatsStore{db},
+		LinkPreviews:   &LinkPreviewStore{db},
 	}
 }
 
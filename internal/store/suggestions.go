@@ -0,0 +1,272 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+const (
+	suggestionsPerUser   = 50
+	suggestionBatchSize  = 500
+	suggestionTagWindow  = 30 * 24 * time.Hour
+	popularSuggestionCap = 100
+)
+
+// Suggestion is an account suggested for a user to follow. MutualCount is
+// how many of the accounts the user follows already follow it and
+// SharedTags how many tags of the user's recent posts it recently posted
+// under. Both are zero for accounts suggested for their popularity alone.
+type Suggestion struct {
+	ID             int64  `json:"id"`
+	Username       string `json:"username"`
+	DisplayName    string `json:"display_name"`
+	AvatarURL      string `json:"avatar_url"`
+	IsPrivate      bool   `json:"is_private"`
+	FollowersCount int    `json:"followers_count"`
+	MutualCount    int    `json:"mutual_count"`
+	SharedTags     int    `json:"shared_tags"`
+}
+
+type SuggestionStore struct {
+	db *sql.DB
+}
+
+// suggestableClause is an SQL condition that holds when the user u may be
+// suggested to the user given as the SQL expression viewer: an active,
+// unsuspended account other than the viewer that the viewer neither
+// follows, has asked to follow, mutes nor dismissed, with no block between
+// the two.
+func suggestableClause(viewer string) string {
+	return fmt.Sprintf(`u.id <> %[1]s AND u.is_active AND u.suspended_at IS NULL AND
+		u.deletion_requested_at IS NULL AND
+		NOT EXISTS (SELECT 1 FROM followers sf WHERE sf.user_id = u.id AND sf.follower_id = %[1]s) AND
+		NOT EXISTS (SELECT 1 FROM follow_requests sr WHERE sr.user_id = u.id AND sr.requester_id = %[1]s) AND
+		NOT EXISTS (SELECT 1 FROM mutes sm WHERE sm.muter_id = %[1]s AND sm.muted_id = u.id) AND
+		NOT EXISTS (SELECT 1 FROM suggestion_dismissals sd WHERE sd.user_id = %[1]s AND sd.dismissed_id = u.id) AND
+		NOT %[2]s`, viewer, blockedBetween(viewer, "u.id"))
+}
+
+// Refresh recomputes the follow suggestions of every active user, a batch
+// of users at a time. Candidates are scored
+//
+//	3 * mutual follows + 2 * shared tags + ln(1 + followers)
+//
+// where mutual follows counts the accounts the user follows that follow the
+// candidate, and shared tags counts the tags the user posted under in the
+// last 30 days that the candidate also used publicly in that time. The
+// most followed accounts are always candidates, so users without follows
+// or posts still get suggestions.
+func (s *SuggestionStore) Refresh(ctx context.Context) error {
+	var after int64
+	for {
+		ids, err := s.nextBatch(ctx, after)
+		if err != nil {
+			return err
+		}
+		if len(ids) == 0 {
+			break
+		}
+
+		if err := s.refreshBatch(ctx, ids); err != nil {
+			return err
+		}
+		after = ids[len(ids)-1]
+	}
+
+	// Users who since went inactive keep no suggestions around.
+	query := `
+		DELETE FROM follow_suggestions fs
+		USING users u
+		WHERE u.id = fs.user_id AND NOT (u.is_active AND u.suspended_at IS NULL)
+	`
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	_, err := s.db.ExecContext(ctx, query)
+	return err
+}
+
+func (s *SuggestionStore) nextBatch(ctx context.Context, after int64) ([]int64, error) {
+	query := `
+		SELECT id FROM users
+		WHERE id > $1 AND is_active AND suspended_at IS NULL
+		ORDER BY id
+		LIMIT $2
+	`
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, after, suggestionBatchSize)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+func (s *SuggestionStore) refreshBatch(ctx context.Context, ids []int64) error {
+	query := `
+		WITH mutuals AS (
+			SELECT f1.follower_id AS user_id, f2.user_id AS candidate_id, COUNT(*) AS n
+			FROM followers f1
+			JOIN followers f2 ON f2.follower_id = f1.user_id
+			WHERE f1.follower_id = ANY($1)
+			GROUP BY 1, 2
+		),
+		viewer_tags AS (
+			SELECT DISTINCT p.user_id, t.tag
+			FROM posts p, unnest(p.tags) AS t(tag)
+			WHERE p.user_id = ANY($1) AND p.created_at > NOW() - make_interval(secs => $2)
+		),
+		tag_authors AS (
+			SELECT vt.user_id, p.user_id AS candidate_id, COUNT(DISTINCT vt.tag) AS n
+			FROM viewer_tags vt
+			JOIN posts p ON p.tags @> ARRAY[vt.tag]
+			WHERE p.visibility = 'public' AND p.created_at > NOW() - make_interval(secs => $2)
+			GROUP BY 1, 2
+		),
+		popular AS (
+			SELECT v.user_id, pu.id AS candidate_id
+			FROM unnest($1::bigint[]) AS v(user_id)
+			CROSS JOIN (
+				SELECT id FROM users
+				WHERE is_active AND suspended_at IS NULL
+				ORDER BY followers_count DESC, id
+				LIMIT $4
+			) pu
+		),
+		candidates AS (
+			SELECT user_id, candidate_id, SUM(mutuals)::int AS mutuals, SUM(tags)::int AS tags
+			FROM (
+				SELECT user_id, candidate_id, n AS mutuals, 0 AS tags FROM mutuals
+				UNION ALL
+				SELECT user_id, candidate_id, 0, n FROM tag_authors
+				UNION ALL
+				SELECT user_id, candidate_id, 0, 0 FROM popular
+			) c
+			GROUP BY 1, 2
+		),
+		scored AS (
+			SELECT c.user_id, c.candidate_id, c.mutuals, c.tags,
+				3 * c.mutuals + 2 * c.tags + ln(1 + u.followers_count::double precision) AS score
+			FROM candidates c
+			JOIN users u ON u.id = c.candidate_id
+			WHERE ` + suggestableClause("c.user_id") + `
+		),
+		ranked AS (
+			SELECT *, row_number() OVER (PARTITION BY user_id ORDER BY score DESC, candidate_id) AS rank
+			FROM scored
+		)
+		INSERT INTO follow_suggestions (user_id, suggested_id, score, mutual_count, shared_tags)
+		SELECT user_id, candidate_id, score, mutuals, tags
+		FROM ranked
+		WHERE rank <= $3
+	`
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	return withTx(s.db, ctx, func(tx *sql.Tx) error {
+		if _, err := tx.ExecContext(ctx, `DELETE FROM follow_suggestions WHERE user_id = ANY($1)`, pq.Array(ids)); err != nil {
+			return err
+		}
+
+		_, err := tx.ExecContext(ctx, query, pq.Array(ids), suggestionTagWindow.Seconds(), suggestionsPerUser, popularSuggestionCap)
+		return err
+	})
+}
+
+// Get returns the best suggestions for userID as of the last refresh.
+// Follows, blocks, mutes and dismissals since then are applied right away.
+// Until the user's first refresh, the most followed accounts are suggested.
+func (s *SuggestionStore) Get(ctx context.Context, userID int64, limit int) ([]Suggestion, error) {
+	query := `
+		SELECT u.id, u.username, u.display_name, u.avatar_url, u.is_private, u.followers_count,
+			fs.mutual_count, fs.shared_tags
+		FROM follow_suggestions fs
+		JOIN users u ON u.id = fs.suggested_id
+		WHERE fs.user_id = $1 AND ` + suggestableClause("$1") + `
+		ORDER BY fs.score DESC, fs.suggested_id
+		LIMIT $2
+	`
+	suggestions, err := s.list(ctx, query, userID, limit)
+	if err != nil || len(suggestions) > 0 {
+		return suggestions, err
+	}
+
+	query = `
+		SELECT u.id, u.username, u.display_name, u.avatar_url, u.is_private, u.followers_count, 0, 0
+		FROM users u
+		WHERE ` + suggestableClause("$1") + `
+		ORDER BY u.followers_count DESC, u.id
+		LIMIT $2
+	`
+	return s.list(ctx, query, userID, limit)
+}
+
+func (s *SuggestionStore) list(ctx context.Context, query string, userID int64, limit int) ([]Suggestion, error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, userID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	suggestions := []Suggestion{}
+	for rows.Next() {
+		var sg Suggestion
+		err := rows.Scan(
+			&sg.ID,
+			&sg.Username,
+			&sg.DisplayName,
+			&sg.AvatarURL,
+			&sg.IsPrivate,
+			&sg.FollowersCount,
+			&sg.MutualCount,
+			&sg.SharedTags,
+		)
+		if err != nil {
+			return nil, err
+		}
+		suggestions = append(suggestions, sg)
+	}
+	return suggestions, rows.Err()
+}
+
+// Dismiss stops suggesting dismissedID to userID. Dismissing an unknown
+// user gives ErrNotFound.
+func (s *SuggestionStore) Dismiss(ctx context.Context, userID, dismissedID int64) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	return withTx(s.db, ctx, func(tx *sql.Tx) error {
+		query := `
+			INSERT INTO suggestion_dismissals (user_id, dismissed_id) VALUES ($1, $2)
+			ON CONFLICT DO NOTHING
+		`
+		if _, err := tx.ExecContext(ctx, query, userID, dismissedID); err != nil {
+			if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23503" {
+				return ErrNotFound
+			}
+			return err
+		}
+
+		query = `DELETE FROM follow_suggestions WHERE user_id = $1 AND suggested_id = $2`
+		_, err := tx.ExecContext(ctx, query, userID, dismissedID)
+		return err
+	})
+}
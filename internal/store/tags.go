@@ -0,0 +1,314 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"regexp"
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+// tagRewriteBatchSize bounds how many posts a rename or merge rewrites per
+// statement, so the GIN index on posts.tags is updated in small steps
+// instead of one long locking transaction.
+const tagRewriteBatchSize = 500
+
+var tagSpaceRx = regexp.MustCompile(`\s+`)
+
+type Tag struct {
+	ID        int64    `json:"id"`
+	Slug      string   `json:"slug"`
+	Name      string   `json:"name"`
+	Aliases   []string `json:"aliases,omitempty"`
+	PostCount int      `json:"post_count,omitempty"`
+	CreatedAt string   `json:"created_at"`
+}
+
+type TagStore struct {
+	db *sql.DB
+}
+
+// queryer is satisfied by both *sql.DB and *sql.Tx.
+type queryer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// NormalizeTag turns a free-form tag into its slug: lower cased, trimmed,
+// without a leading '#' and with inner whitespace collapsed to '-'.
+func NormalizeTag(name string) string {
+	slug := strings.TrimSpace(name)
+	slug = strings.TrimLeft(slug, "#")
+	slug = strings.ToLower(strings.TrimSpace(slug))
+	return tagSpaceRx.ReplaceAllString(slug, "-")
+}
+
+// canonicalTags normalises names and follows aliases to their canonical
+// slug, keeping the first occurrence of each tag.
+func canonicalTags(ctx context.Context, q queryer, names []string) ([]string, error) {
+	slugs := []string{}
+	seen := map[string]bool{}
+	for _, name := range names {
+		slug := NormalizeTag(name)
+		if slug == "" || seen[slug] {
+			continue
+		}
+		seen[slug] = true
+		slugs = append(slugs, slug)
+	}
+	if len(slugs) == 0 {
+		return slugs, nil
+	}
+
+	query := `
+		SELECT a.alias, t.slug FROM tag_aliases a
+		JOIN tags t ON t.id = a.tag_id
+		WHERE a.alias = ANY($1)
+	`
+	rows, err := q.QueryContext(ctx, query, pq.Array(slugs))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	aliases := map[string]string{}
+	for rows.Next() {
+		var alias, slug string
+		if err := rows.Scan(&alias, &slug); err != nil {
+			return nil, err
+		}
+		aliases[alias] = slug
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	canonical := []string{}
+	seen = map[string]bool{}
+	for _, slug := range slugs {
+		if target, ok := aliases[slug]; ok {
+			slug = target
+		}
+		if seen[slug] {
+			continue
+		}
+		seen[slug] = true
+		canonical = append(canonical, slug)
+	}
+	return canonical, nil
+}
+
+// resolveTags canonicalises names and registers the tags that do not exist
+// yet.
+func resolveTags(ctx context.Context, tx *sql.Tx, names []string) ([]string, error) {
+	slugs, err := canonicalTags(ctx, tx, names)
+	if err != nil {
+		return nil, err
+	}
+	if len(slugs) == 0 {
+		return slugs, nil
+	}
+
+	query := `
+		INSERT INTO tags (slug, name)
+		SELECT t, t FROM unnest($1::varchar[]) AS t
+		ON CONFLICT (slug) DO NOTHING
+	`
+	if _, err := tx.ExecContext(ctx, query, pq.Array(slugs)); err != nil {
+		return nil, err
+	}
+	return slugs, nil
+}
+
+// GetBySlug looks a tag up by its slug or one of its aliases.
+func (s *TagStore) GetBySlug(ctx context.Context, slug string) (*Tag, error) {
+	query := `
+		SELECT t.id, t.slug, t.name, t.created_at,
+			COALESCE(array_agg(a.alias ORDER BY a.alias) FILTER (WHERE a.alias IS NOT NULL), '{}')
+		FROM tags t
+		LEFT JOIN tag_aliases a ON a.tag_id = t.id
+		WHERE t.slug = $1 OR t.id = (SELECT tag_id FROM tag_aliases WHERE alias = $1)
+		GROUP BY t.id
+	`
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	tag := &Tag{}
+	err := s.db.QueryRowContext(ctx, query, NormalizeTag(slug)).Scan(
+		&tag.ID,
+		&tag.Slug,
+		&tag.Name,
+		&tag.CreatedAt,
+		pq.Array(&tag.Aliases),
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrNotFound
+		default:
+			return nil, err
+		}
+	}
+	return tag, nil
+}
+
+// Rename gives a tag a new display name and slug. The old slug stays
+// behind as an alias and existing posts are rewritten to the new slug. A
+// slug that is an alias of another tag gives ErrConflict.
+func (s *TagStore) Rename(ctx context.Context, slug, name string) (*Tag, error) {
+	from := NormalizeTag(slug)
+	to := NormalizeTag(name)
+	if to == "" {
+		return nil, ErrNotFound
+	}
+
+	err := withTx(s.db, ctx, func(tx *sql.Tx) error {
+		var id int64
+		query := `SELECT id FROM tags WHERE slug = $1 FOR UPDATE`
+		if err := tx.QueryRowContext(ctx, query, from).Scan(&id); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return ErrNotFound
+			}
+			return err
+		}
+
+		query = `UPDATE tags SET slug = $1, name = $2 WHERE id = $3`
+		if _, err := tx.ExecContext(ctx, query, to, strings.TrimSpace(name), id); err != nil {
+			if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+				return ErrConflict
+			}
+			return err
+		}
+
+		if from == to {
+			return nil
+		}
+
+		// Taking back one of the tag's own aliases turns it into the slug;
+		// an alias of another tag stays with that tag.
+		var aliasOf int64
+		query = `SELECT tag_id FROM tag_aliases WHERE alias = $1 FOR UPDATE`
+		switch err := tx.QueryRowContext(ctx, query, to).Scan(&aliasOf); {
+		case errors.Is(err, sql.ErrNoRows):
+		case err != nil:
+			return err
+		case aliasOf != id:
+			return ErrConflict
+		default:
+			if _, err := tx.ExecContext(ctx, `DELETE FROM tag_aliases WHERE alias = $1 AND tag_id = $2`, to, id); err != nil {
+				return err
+			}
+		}
+		return s.addAlias(ctx, tx, from, id)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if from != to {
+		if err := s.rewritePosts(ctx, from, to); err != nil {
+			return nil, err
+		}
+	}
+	return s.GetBySlug(ctx, to)
+}
+
+// Merge folds the tag from into the tag into: from and its aliases become
+// aliases of into and every post tagged from is retagged.
+func (s *TagStore) Merge(ctx context.Context, fromSlug, intoSlug string) (*Tag, error) {
+	from := NormalizeTag(fromSlug)
+	into := NormalizeTag(intoSlug)
+	if from == into {
+		return nil, ErrConflict
+	}
+
+	err := withTx(s.db, ctx, func(tx *sql.Tx) error {
+		var fromID, intoID int64
+		query := `SELECT id FROM tags WHERE slug = $1 FOR UPDATE`
+		if err := tx.QueryRowContext(ctx, query, from).Scan(&fromID); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return ErrNotFound
+			}
+			return err
+		}
+		if err := tx.QueryRowContext(ctx, query, into).Scan(&intoID); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return ErrNotFound
+			}
+			return err
+		}
+
+		query = `UPDATE tag_aliases SET tag_id = $1 WHERE tag_id = $2`
+		if _, err := tx.ExecContext(ctx, query, intoID, fromID); err != nil {
+			return err
+		}
+
+		if _, err := tx.ExecContext(ctx, `DELETE FROM tags WHERE id = $1`, fromID); err != nil {
+			return err
+		}
+		return s.addAlias(ctx, tx, from, intoID)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.rewritePosts(ctx, from, into); err != nil {
+		return nil, err
+	}
+	return s.GetBySlug(ctx, into)
+}
+
+func (s *TagStore) addAlias(ctx context.Context, tx *sql.Tx, alias string, tagID int64) error {
+	query := `
+		INSERT INTO tag_aliases (alias, tag_id) VALUES ($1, $2)
+		ON CONFLICT (alias) DO UPDATE SET tag_id = EXCLUDED.tag_id
+	`
+	_, err := tx.ExecContext(ctx, query, alias, tagID)
+	return err
+}
+
+// rewritePosts replaces the slug from with to on every post and hashtag in
+// batches of tagRewriteBatchSize rows.
+func (s *TagStore) rewritePosts(ctx context.Context, from, to string) error {
+	queries := []string{`
+		UPDATE posts p
+		SET tags = ARRAY(
+			SELECT x.tag FROM unnest(array_replace(p.tags, $1::varchar, $2::varchar)) WITH ORDINALITY AS x(tag, n)
+			GROUP BY x.tag
+			ORDER BY MIN(x.n)
+		), explicit_tags = ARRAY(
+			SELECT x.tag FROM unnest(array_replace(p.explicit_tags, $1::varchar, $2::varchar)) WITH ORDINALITY AS x(tag, n)
+			GROUP BY x.tag
+			ORDER BY MIN(x.n)
+		), version = version + 1
+		WHERE p.id IN (
+			SELECT id FROM posts WHERE tags @> ARRAY[$1::varchar] LIMIT $3
+		)
+	`, `
+		UPDATE hashtags SET tag = $2
+		WHERE id IN (SELECT id FROM hashtags WHERE tag = $1 LIMIT $3)
+	`}
+
+	for _, query := range queries {
+		for {
+			batchCtx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+			result, err := s.db.ExecContext(batchCtx, query, from, to, tagRewriteBatchSize)
+			cancel()
+			if err != nil {
+				return err
+			}
+
+			rows, err := result.RowsAffected()
+			if err != nil {
+				return err
+			}
+			if rows == 0 {
+				break
+			}
+		}
+	}
+	return nil
+}
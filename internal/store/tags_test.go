@@ -0,0 +1,43 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+)
+
+func TestRenameKeepsAliasesOfOtherTags(t *testing.T) {
+	db := newTestDB(t)
+	ctx := context.Background()
+	tags := &TagStore{db}
+
+	err := withTx(db, ctx, func(tx *sql.Tx) error {
+		_, err := resolveTags(ctx, tx, []string{"rename-test-a", "rename-test-b"})
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		db.Exec(`DELETE FROM tags WHERE slug LIKE 'rename-test-%'`)
+	})
+
+	b, err := tags.Rename(ctx, "rename-test-b", "rename-test-c")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := tags.Rename(ctx, "rename-test-a", "rename-test-b"); !errors.Is(err, ErrConflict) {
+		t.Fatalf("Rename to an alias of another tag: got %v, want ErrConflict", err)
+	}
+
+	// The alias still belongs to b, which may take it back.
+	got, err := tags.Rename(ctx, "rename-test-c", "rename-test-b")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.ID != b.ID {
+		t.Errorf("Rename back to its own alias gave tag %d, want %d", got.ID, b.ID)
+	}
+}
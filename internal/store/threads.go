@@ -0,0 +1,171 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+type Thread struct {
+	ID        int64              `json:"id"`
+	UserID    int64              `json:"user_id"`
+	CreatedAt string             `json:"created_at"`
+	Posts     []PostWithMetadata `json:"posts"`
+}
+
+// PostNavigation links a post to its neighbours in its thread and series.
+type PostNavigation struct {
+	Thread *NavigationLinks `json:"thread,omitempty"`
+	Series *NavigationLinks `json:"series,omitempty"`
+}
+
+type NavigationLinks struct {
+	ID       int64  `json:"id"`
+	Title    string `json:"title,omitempty"`
+	Position int    `json:"position"`
+	Total    int    `json:"total"`
+	Prev     *int64 `json:"prev,omitempty"`
+	Next     *int64 `json:"next,omitempty"`
+}
+
+type ThreadStore struct {
+	db *sql.DB
+}
+
+// Create inserts the parts of a thread in order, all or none. Each part
+// gets its ID, thread ID and position filled in.
+func (s *ThreadStore) Create(ctx context.Context, userID int64, parts []*Post) (*Thread, error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	thread := &Thread{UserID: userID}
+	err := withTx(s.db, ctx, func(tx *sql.Tx) error {
+		query := `INSERT INTO threads (user_id) VALUES ($1) RETURNING id, created_at`
+		if err := tx.QueryRowContext(ctx, query, userID).Scan(&thread.ID, &thread.CreatedAt); err != nil {
+			return err
+		}
+
+		for i, post := range parts {
+			position := i + 1
+			post.UserID = userID
+			post.ThreadID = &thread.ID
+			post.ThreadPosition = &position
+			if err := createPost(ctx, tx, post); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	thread.Posts = make([]PostWithMetadata, len(parts))
+	for i, post := range parts {
+		thread.Posts[i] = PostWithMetadata{Post: *post, ThreadParts: len(parts)}
+	}
+	return thread, nil
+}
+
+// GetByID returns a thread with the parts visible to the viewer in order.
+func (s *ThreadStore) GetByID(ctx context.Context, id, viewerID int64) (*Thread, error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	thread := &Thread{}
+	query := `SELECT id, user_id, created_at FROM threads WHERE id = $1`
+	if err := s.db.QueryRowContext(ctx, query, id).Scan(&thread.ID, &thread.UserID, &thread.CreatedAt); err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	query = `
+		SELECT ` + postListColumns + `
+		FROM posts p
+		JOIN users u ON u.id = p.user_id
+		WHERE p.thread_id = $1 AND ` + visiblePostsClause(2) + `
+		ORDER BY p.thread_position
+	`
+	rows, err := s.db.QueryContext(ctx, query, id, viewerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	thread.Posts, err = scanPostsWithMetadata(rows)
+	if err != nil {
+		return nil, err
+	}
+	if len(thread.Posts) == 0 {
+		return nil, ErrNotFound
+	}
+	return thread, nil
+}
+
+// GetNavigation returns where the post sits in its thread and series, with
+// the previous and next parts the viewer may see. It returns nil when the
+// post belongs to neither.
+func (s *PostStore) GetNavigation(ctx context.Context, post *Post, viewerID int64) (*PostNavigation, error) {
+	if post.ThreadID == nil && post.SeriesID == nil {
+		return nil, nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	nav := &PostNavigation{}
+	if post.ThreadID != nil {
+		links, err := s.navigationLinks(ctx, "thread", *post.ThreadID, post.ID, viewerID)
+		if err != nil {
+			return nil, err
+		}
+		nav.Thread = links
+	}
+	if post.SeriesID != nil {
+		links, err := s.navigationLinks(ctx, "series", *post.SeriesID, post.ID, viewerID)
+		if err != nil {
+			return nil, err
+		}
+		query := `SELECT title FROM series WHERE id = $1`
+		if err := s.db.QueryRowContext(ctx, query, *post.SeriesID).Scan(&links.Title); err != nil {
+			return nil, err
+		}
+		nav.Series = links
+	}
+	return nav, nil
+}
+
+// navigationLinks locates postID among the visible posts of a thread or
+// series; group is either "thread" or "series" and picks the columns.
+func (s *PostStore) navigationLinks(ctx context.Context, group string, groupID, postID, viewerID int64) (*NavigationLinks, error) {
+	query := fmt.Sprintf(`
+		SELECT position, total, prev, next FROM (
+			SELECT p.id,
+				ROW_NUMBER() OVER w AS position,
+				COUNT(*) OVER () AS total,
+				LAG(p.id) OVER w AS prev,
+				LEAD(p.id) OVER w AS next
+			FROM posts p
+			WHERE p.%[1]s_id = $1 AND (p.id = $2 OR %[2]s)
+			WINDOW w AS (ORDER BY p.%[1]s_position)
+		) n
+		WHERE n.id = $2
+	`, group, visiblePostsClause(3))
+
+	links := &NavigationLinks{ID: groupID}
+	err := s.db.QueryRowContext(ctx, query, groupID, postID, viewerID).Scan(
+		&links.Position,
+		&links.Total,
+		&links.Prev,
+		&links.Next,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return links, nil
+}
@@ -0,0 +1,136 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"time"
+)
+
+type trendingWindow struct {
+	period   time.Duration
+	halfLife time.Duration
+}
+
+// TrendingWindows are the sliding windows trending tags are computed over.
+// Within a window every post counts for 0.5^(age/halfLife), so recent
+// activity outweighs older activity.
+var TrendingWindows = map[string]trendingWindow{
+	"day":  {period: 24 * time.Hour, halfLife: 6 * time.Hour},
+	"week": {period: 7 * 24 * time.Hour, halfLife: 36 * time.Hour},
+}
+
+const trendingTagsPerWindow = 100
+
+type TrendingTag struct {
+	Slug        string  `json:"slug"`
+	Name        string  `json:"name"`
+	Score       float64 `json:"score"`
+	PostCount   int     `json:"post_count"`
+	RefreshedAt string  `json:"refreshed_at"`
+}
+
+// RefreshTrending recomputes the trending_tags table from public posts for
+// every window, leaving out accounts pending deletion.
+func (s *TagStore) RefreshTrending(ctx context.Context) error {
+	query := `
+		INSERT INTO trending_tags (time_window, slug, score, post_count)
+		SELECT $1, t.tag,
+			SUM(power(0.5, extract(epoch FROM NOW() - p.created_at) / $3)),
+			COUNT(*)
+		FROM posts p
+		JOIN users u ON u.id = p.user_id
+		CROSS JOIN unnest(p.tags) AS t(tag)
+		WHERE p.visibility = 'public' AND p.created_at > NOW() - make_interval(secs => $2) AND
+			(p.expires_at IS NULL OR p.expires_at > NOW()) AND u.deletion_requested_at IS NULL
+		GROUP BY t.tag
+		ORDER BY 3 DESC
+		LIMIT $4
+	`
+
+	return withTx(s.db, ctx, func(tx *sql.Tx) error {
+		if _, err := tx.ExecContext(ctx, `DELETE FROM trending_tags`); err != nil {
+			return err
+		}
+
+		for name, w := range TrendingWindows {
+			_, err := tx.ExecContext(ctx, query, name, w.period.Seconds(), w.halfLife.Seconds(), trendingTagsPerWindow)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Trending lists the top tags of a window as of the last refresh.
+func (s *TagStore) Trending(ctx context.Context, window string, limit int) ([]TrendingTag, error) {
+	query := `
+		SELECT tt.slug, COALESCE(t.name, tt.slug), tt.score, tt.post_count, tt.refreshed_at
+		FROM trending_tags tt
+		LEFT JOIN tags t ON t.slug = tt.slug
+		WHERE tt.time_window = $1
+		ORDER BY tt.score DESC
+		LIMIT $2
+	`
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, window, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tags := []TrendingTag{}
+	for rows.Next() {
+		var t TrendingTag
+		if err := rows.Scan(&t.Slug, &t.Name, &t.Score, &t.PostCount, &t.RefreshedAt); err != nil {
+			return nil, err
+		}
+		tags = append(tags, t)
+	}
+	return tags, rows.Err()
+}
+
+// Autocomplete suggests tags starting with or similar to q, counting only
+// the posts the viewer may see and leaving out tags with none.
+func (s *TagStore) Autocomplete(ctx context.Context, q string, viewerID int64, limit int) ([]Tag, error) {
+	q = NormalizeTag(q)
+	if q == "" {
+		return []Tag{}, nil
+	}
+
+	query := `
+		SELECT t.id, t.slug, t.name, t.created_at, COUNT(p.id) AS post_count
+		FROM tags t
+		JOIN posts p ON p.tags @> ARRAY[t.slug] AND ` + visiblePostsClause(3) + `
+		WHERE t.slug LIKE $4 || '%' OR t.slug % $1
+		GROUP BY t.id
+		ORDER BY t.slug LIKE $4 || '%' DESC, similarity(t.slug, $1) DESC, post_count DESC
+		LIMIT $2
+	`
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, q, limit, viewerID, escapeLike(q))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tags := []Tag{}
+	for rows.Next() {
+		var t Tag
+		if err := rows.Scan(&t.ID, &t.Slug, &t.Name, &t.CreatedAt, &t.PostCount); err != nil {
+			return nil, err
+		}
+		tags = append(tags, t)
+	}
+	return tags, rows.Err()
+}
+
+// escapeLike escapes the LIKE wildcards in s.
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
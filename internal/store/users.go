@@ -5,21 +5,40 @@ import (
 	"crypto/sha256"
 	"database/sql"
 	"encoding/hex"
+	"errors"
 	"time"
 
+	"github.com/lib/pq"
 	"golang.org/x/crypto/bcrypt"
 )
 
+const (
+	SensitiveExpand   = "expand"
+	SensitiveCollapse = "collapse"
+	SensitiveHide     = "hide"
+)
+
 type User struct {
-	ID        int64     `json:"id"`
-	Username  string    `json:"username"`
-	Email     string    `json:"email"`
-	Password  password  `json:"-"`
-	CreatedAt string    `json:"created_at"`
-	Comment   []Comment `json:"comments"`
-	IsActive  bool      `json:"is_active"`
-	RoleID    int64     `json:"role_id"`
-	Role      Role      `json:"role"`
+	ID             int64     `json:"id"`
+	Username       string    `json:"username"`
+	Email          string    `json:"email"`
+	Password       password  `json:"-"`
+	CreatedAt      string    `json:"created_at"`
+	Comment        []Comment `json:"comments"`
+	IsActive       bool      `json:"is_active"`
+	RoleID         int64     `json:"role_id"`
+	Role           Role      `json:"role"`
+	DisplayName    string    `json:"display_name"`
+	Bio            string    `json:"bio"`
+	Location       string    `json:"location"`
+	Links          []string  `json:"links"`
+	AvatarURL      string    `json:"avatar_url"`
+	FollowersCount int       `json:"followers_count"`
+	FollowingCount int       `json:"following_count"`
+	IsPrivate      bool      `json:"is_private"`
+
+	UsernameChangedAt   *time.Time `json:"username_changed_at,omitempty"`
+	DeletionRequestedAt *time.Time `json:"deletion_requested_at,omitempty"`
 }
 
 type password struct {
@@ -39,13 +58,25 @@ func (p *password) Set(text string) error {
 	return nil
 }
 
+// Compare checks text against the stored hash.
+func (p *password) Compare(text string) error {
+	return bcrypt.CompareHashAndPassword(p.hash, []byte(text))
+}
+
 type UserStore struct {
 	db *sql.DB
 }
 
 func (s UserStore) Create(ctx context.Context, tx *sql.Tx, user *User) error {
+	// Names given up in a rename stay reserved for their previous owner
+	// until the grace period ends.
 	query := `
-		INSERT INTO users(username, password, email,role_id) VALUES($1, $2, $3,(SELECT id FROM roles WHERE name=$4)) RETURNING id, created_at
+		INSERT INTO users(username, password, email,role_id)
+		SELECT $1, $2, $3,(SELECT id FROM roles WHERE name=$4)
+		WHERE NOT EXISTS (
+			SELECT 1 FROM username_history WHERE username = $1 AND reserved_until > NOW()
+		)
+		RETURNING id, created_at
 	`
 	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
 	defer cancel()
@@ -54,7 +85,7 @@ func (s UserStore) Create(ctx context.Context, tx *sql.Tx, user *User) error {
 	if role == "" {
 		role = "user"
 	}
-	if err := s.db.QueryRowContext(
+	if err := tx.QueryRowContext(
 		ctx,
 		query,
 		user.Username,
@@ -66,23 +97,41 @@ func (s UserStore) Create(ctx context.Context, tx *sql.Tx, user *User) error {
 		&user.CreatedAt,
 	); err != nil {
 		switch {
-		case err.Error() == `pq: duplicate key value violates unique constraint "users_email_key"`:
-			return ErrDuplicateEmail
-		case err.Error() == `pq: duplicate key value violates unique constraint "users_username_key"`:
+		case errors.Is(err, sql.ErrNoRows):
 			return ErrDuplicateUsername
 		default:
-			return err
+			return userConstraintError(err)
 		}
 	}
 	return nil
 }
 
+// userConstraintError maps unique violations on the users table to their
+// store errors by constraint name.
+func userConstraintError(err error) error {
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) || pqErr.Code != "23505" {
+		return err
+	}
+
+	switch pqErr.Constraint {
+	case "users_email_key":
+		return ErrDuplicateEmail
+	case "users_username_key":
+		return ErrDuplicateUsername
+	default:
+		return err
+	}
+}
+
 func (s UserStore) GetByID(ctx context.Context, userID int64) (*User, error) {
 	user := &User{}
 	query := `
-		SELECT users.id, username, email, password, created_at, is_active,roles.*
+		SELECT users.id, username, email, password, created_at, is_active,
+			display_name, bio, location, links, avatar_url, followers_count, following_count,
+			is_private, username_changed_at, roles.*
 		FROM users 
-		JOIN roles ON (user.role_id=roles.id)
+		JOIN roles ON (users.role_id=roles.id)
 		WHERE users.id=$1 AND is_active=true;
 	`
 
@@ -96,6 +145,15 @@ func (s UserStore) GetByID(ctx context.Context, userID int64) (*User, error) {
 		&user.Password.hash,
 		&user.CreatedAt,
 		&user.IsActive,
+		&user.DisplayName,
+		&user.Bio,
+		&user.Location,
+		pq.Array(&user.Links),
+		&user.AvatarURL,
+		&user.FollowersCount,
+		&user.FollowingCount,
+		&user.IsPrivate,
+		&user.UsernameChangedAt,
 		&user.Role.Id,
 		&user.Role.Name,
 		&user.Role.Level,
@@ -217,10 +275,213 @@ func (s *UserStore) delete(ctx context.Context, tx *sql.Tx, id int64) error {
 	return err
 }
 
+// SetPrivate turns follow approval on or off for a user. Making the account
+// public again approves every pending request; the IDs of those requesters
+// are returned.
+func (s *UserStore) SetPrivate(ctx context.Context, userID int64, private bool) ([]int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	var approved []int64
+	err := withTx(s.db, ctx, func(tx *sql.Tx) error {
+		query := `UPDATE users SET is_private = $1 WHERE id = $2`
+		if _, err := tx.ExecContext(ctx, query, private, userID); err != nil {
+			return err
+		}
+		if private {
+			return nil
+		}
+
+		var err error
+		approved, err = approveAllFollowRequests(ctx, tx, userID)
+		return err
+	})
+	return approved, err
+}
+
+// GetByUsername returns the active user currently holding username.
+func (s *UserStore) GetByUsername(ctx context.Context, username string) (*User, error) {
+	query := `SELECT id FROM users WHERE username = $1 AND is_active = true`
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	var id int64
+	if err := s.db.QueryRowContext(ctx, query, username).Scan(&id); err != nil {
+		switch err {
+		case sql.ErrNoRows:
+			return nil, ErrNotFound
+		default:
+			return nil, err
+		}
+	}
+	return s.GetByID(ctx, id)
+}
+
+// ResolveFormerUsername returns the ID of the account that gave up username
+// less than its grace period ago.
+func (s *UserStore) ResolveFormerUsername(ctx context.Context, username string) (int64, error) {
+	query := `
+		SELECT u.id FROM username_history h
+		JOIN users u ON u.id = h.user_id
+		WHERE h.username = $1 AND h.reserved_until > NOW() AND u.is_active = true
+		ORDER BY h.changed_at DESC
+		LIMIT 1
+	`
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	var id int64
+	if err := s.db.QueryRowContext(ctx, query, username).Scan(&id); err != nil {
+		switch err {
+		case sql.ErrNoRows:
+			return 0, ErrNotFound
+		default:
+			return 0, err
+		}
+	}
+	return id, nil
+}
+
+// ChangeUsername renames a user at most once per cooldown. The old name is
+// kept in the history and stays reserved for the user during grace. Taking a
+// name held or reserved by someone else gives ErrDuplicateUsername.
+func (s *UserStore) ChangeUsername(ctx context.Context, userID int64, username string, cooldown, grace time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	return withTx(s.db, ctx, func(tx *sql.Tx) error {
+		var current string
+		var changedAt *time.Time
+		query := `SELECT username, username_changed_at FROM users WHERE id = $1 FOR UPDATE`
+		if err := tx.QueryRowContext(ctx, query, userID).Scan(&current, &changedAt); err != nil {
+			switch err {
+			case sql.ErrNoRows:
+				return ErrNotFound
+			default:
+				return err
+			}
+		}
+
+		if current == username {
+			return nil
+		}
+		if changedAt != nil && time.Since(*changedAt) < cooldown {
+			return ErrUsernameCooldown
+		}
+
+		var reserved bool
+		query = `
+			SELECT EXISTS (
+				SELECT 1 FROM username_history
+				WHERE username = $1 AND reserved_until > NOW() AND user_id <> $2
+			)
+		`
+		if err := tx.QueryRowContext(ctx, query, username, userID).Scan(&reserved); err != nil {
+			return err
+		}
+		if reserved {
+			return ErrDuplicateUsername
+		}
+
+		query = `UPDATE users SET username = $1, username_changed_at = NOW() WHERE id = $2`
+		if _, err := tx.ExecContext(ctx, query, username, userID); err != nil {
+			return userConstraintError(err)
+		}
+
+		// Reclaiming a reserved name ends its reservation.
+		query = `UPDATE username_history SET reserved_until = NOW() WHERE username = $1 AND user_id = $2`
+		if _, err := tx.ExecContext(ctx, query, username, userID); err != nil {
+			return err
+		}
+
+		query = `
+			INSERT INTO username_history (user_id, username, reserved_until)
+			VALUES ($1, $2, NOW() + make_interval(secs => $3))
+		`
+		_, err := tx.ExecContext(ctx, query, userID, current, grace.Seconds())
+		return err
+	})
+}
+
+// UpdateProfile saves the public profile fields of a user.
+func (s *UserStore) UpdateProfile(ctx context.Context, user *User) error {
+	query := `
+		UPDATE users SET display_name = $1, bio = $2, location = $3, links = $4
+		WHERE id = $5
+	`
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	if user.Links == nil {
+		user.Links = []string{}
+	}
+
+	result, err := s.db.ExecContext(ctx, query, user.DisplayName, user.Bio, user.Location, pq.Array(user.Links), user.ID)
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// SetAvatar stores a user's avatar image and points their profile at
+// avatarURL.
+func (s *UserStore) SetAvatar(ctx context.Context, userID int64, contentType string, data []byte, avatarURL string) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	return withTx(s.db, ctx, func(tx *sql.Tx) error {
+		query := `
+			INSERT INTO user_avatars (user_id, content_type, data) VALUES ($1, $2, $3)
+			ON CONFLICT (user_id) DO UPDATE SET
+				content_type = EXCLUDED.content_type, data = EXCLUDED.data, updated_at = NOW()
+		`
+		if _, err := tx.ExecContext(ctx, query, userID, contentType, data); err != nil {
+			return err
+		}
+
+		_, err := tx.ExecContext(ctx, `UPDATE users SET avatar_url = $1 WHERE id = $2`, avatarURL, userID)
+		return err
+	})
+}
+
+// GetAvatar returns the content type and bytes of a user's avatar.
+// Inactive accounts, including those pending deletion, have none.
+func (s *UserStore) GetAvatar(ctx context.Context, userID int64) (string, []byte, error) {
+	query := `
+		SELECT a.content_type, a.data FROM user_avatars a
+		JOIN users u ON u.id = a.user_id
+		WHERE a.user_id = $1 AND u.is_active = true
+	`
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	var contentType string
+	var data []byte
+	if err := s.db.QueryRowContext(ctx, query, userID).Scan(&contentType, &data); err != nil {
+		switch err {
+		case sql.ErrNoRows:
+			return "", nil, ErrNotFound
+		default:
+			return "", nil, err
+		}
+	}
+	return contentType, data, nil
+}
+
 func (s *UserStore) GetByEmail(ctx context.Context, email string) (*User, error) {
+	// Accounts pending deletion are still found so logging in can restore
+	// them.
 	query := `
-		SELECT id,username,email,password,created_at FROM users
-		WHERE email = $1 is_active=true
+		SELECT id,username,email,password,created_at,is_active,deletion_requested_at FROM users
+		WHERE email = $1 AND (is_active = true OR deletion_requested_at IS NOT NULL)
 	`
 	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
 	defer cancel()
@@ -234,6 +495,7 @@ func (s *UserStore) GetByEmail(ctx context.Context, email string) (*User, error)
 		&user.Password.hash,
 		&user.CreatedAt,
 		&user.IsActive,
+		&user.DeletionRequestedAt,
 	)
 
 	if err != nil {
@@ -0,0 +1,79 @@
+package store
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestActivateAndGetByEmail(t *testing.T) {
+	db := newTestDB(t)
+	users := &UserStore{db}
+	ctx := context.Background()
+
+	user := &User{Username: fmt.Sprintf("invitee_%d", time.Now().UnixNano())}
+	user.Email = user.Username + "@example.com"
+	if err := user.Password.Set("password"); err != nil {
+		t.Fatal(err)
+	}
+
+	token := "invitation-" + user.Username
+	hash := sha256.Sum256([]byte(token))
+	if err := users.CreateAndInvite(ctx, user, hex.EncodeToString(hash[:]), time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if err := users.purge(context.Background(), user.ID); err != nil {
+			t.Errorf("deleting test user: %v", err)
+		}
+	})
+
+	if _, err := users.GetByEmail(ctx, user.Email); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("GetByEmail before activation: got %v, want ErrNotFound", err)
+	}
+
+	if err := users.Activate(ctx, token); err != nil {
+		t.Fatalf("Activate: %v", err)
+	}
+	if err := users.Activate(ctx, token); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Activate with a used token: got %v, want ErrNotFound", err)
+	}
+
+	got, err := users.GetByEmail(ctx, user.Email)
+	if err != nil {
+		t.Fatalf("GetByEmail: %v", err)
+	}
+	if got.ID != user.ID || !got.IsActive || got.DeletionRequestedAt != nil {
+		t.Fatalf("GetByEmail = %+v, want active user %d", got, user.ID)
+	}
+	if err := got.Password.Compare("password"); err != nil {
+		t.Fatalf("stored password does not match: %v", err)
+	}
+
+	if _, err := users.RequestDeletion(ctx, user.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err = users.GetByEmail(ctx, user.Email)
+	if err != nil {
+		t.Fatalf("GetByEmail pending deletion: %v", err)
+	}
+	if got.IsActive || got.DeletionRequestedAt == nil {
+		t.Fatalf("GetByEmail pending deletion = %+v, want inactive with deletion_requested_at", got)
+	}
+
+	if err := users.Restore(ctx, user.ID, time.Hour); err != nil {
+		t.Fatalf("Restore: %v", err)
+	}
+	got, err = users.GetByEmail(ctx, user.Email)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !got.IsActive || got.DeletionRequestedAt != nil {
+		t.Fatalf("GetByEmail after restore = %+v, want active", got)
+	}
+}
@@ -0,0 +1,67 @@
+package store
+
+import (
+	"context"
+	"strings"
+)
+
+// UserSummary is the compact view of a user returned by searches.
+type UserSummary struct {
+	ID          int64  `json:"id"`
+	Username    string `json:"username"`
+	DisplayName string `json:"display_name"`
+	AvatarURL   string `json:"avatar_url"`
+	Following   bool   `json:"following"`
+}
+
+// Search finds active users whose username or display name starts with or
+// resembles q. Accounts the viewer follows come first, then accounts
+// followed by those, then the rest by closeness of the match.
+func (s *UserStore) Search(ctx context.Context, q string, viewerID int64, limit int) ([]UserSummary, error) {
+	q = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(q), "@")))
+	if q == "" {
+		return []UserSummary{}, nil
+	}
+
+	query := `
+		SELECT u.id, u.username, u.display_name, u.avatar_url, f.user_id IS NOT NULL AS following
+		FROM users u
+		LEFT JOIN followers f ON f.user_id = u.id AND f.follower_id = $2
+		WHERE u.is_active = true AND u.suspended_at IS NULL AND u.id <> $2 AND
+			NOT ` + blockedBetween("$2", "u.id") + ` AND (
+			lower(u.username) LIKE $4 || '%' OR
+			lower(u.display_name) LIKE $4 || '%' OR
+			u.username % $1 OR
+			u.display_name % $1
+		)
+		ORDER BY
+			f.user_id IS NOT NULL DESC,
+			EXISTS (
+				SELECT 1 FROM followers f1
+				JOIN followers f2 ON f2.follower_id = f1.user_id
+				WHERE f1.follower_id = $2 AND f2.user_id = u.id
+			) DESC,
+			(lower(u.username) LIKE $4 || '%' OR lower(u.display_name) LIKE $4 || '%') DESC,
+			GREATEST(similarity(u.username, $1), similarity(u.display_name, $1)) DESC,
+			u.username
+		LIMIT $3
+	`
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, q, viewerID, limit, escapeLike(q))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	users := []UserSummary{}
+	for rows.Next() {
+		var u UserSummary
+		if err := rows.Scan(&u.ID, &u.Username, &u.DisplayName, &u.AvatarURL, &u.Following); err != nil {
+			return nil, err
+		}
+		users = append(users, u)
+	}
+	return users, rows.Err()
+}
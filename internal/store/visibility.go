@@ -0,0 +1,53 @@
+package store
+
+import "fmt"
+
+const (
+	VisibilityPublic    = "public"
+	VisibilityFollowers = "followers"
+	VisibilityUnlisted  = "unlisted"
+	VisibilityPrivate   = "private"
+)
+
+// VisibleTo reports whether a viewer may open the post by ID. Unlisted posts
+// are reachable by anyone holding the link; followers-only posts need
+// isFollower; private posts are only for their author.
+func (p *Post) VisibleTo(viewerID int64, isFollower bool) bool {
+	if p.UserID == viewerID {
+		return true
+	}
+
+	switch p.Visibility {
+	case VisibilityPublic, VisibilityUnlisted:
+		return true
+	case VisibilityFollowers:
+		return isFollower
+	default:
+		return false
+	}
+}
+
+// visiblePostsClause restricts the posts aliased p to the ones that may be
+// listed to the viewer bound at placeholder $n: their own posts, public
+// posts and followers-only posts of accounts they follow. Unlisted posts
+// never show up in listings of other users, expired posts and posts of
+// accounts pending deletion in no listing, and posts of users blocking the
+// viewer, or blocked by them, are left out.
+func visiblePostsClause(n int) string {
+	return visiblePostsClauseFor("p", n)
+}
+
+// visiblePostsClauseFor is visiblePostsClause for posts aliased a.
+func visiblePostsClauseFor(a string, n int) string {
+	viewer := fmt.Sprintf("$%d", n)
+	return fmt.Sprintf(`(
+		(%[1]s.expires_at IS NULL OR %[1]s.expires_at > NOW()) AND
+		NOT EXISTS (SELECT 1 FROM users vu WHERE vu.id = %[1]s.user_id AND vu.deletion_requested_at IS NOT NULL) AND (
+			%[1]s.user_id = %[2]s OR
+			%[1]s.visibility = 'public' OR
+			(%[1]s.visibility = 'followers' AND EXISTS (
+				SELECT 1 FROM followers vf WHERE vf.user_id = %[1]s.user_id AND vf.follower_id = %[2]s
+			))
+		) AND NOT %[3]s
+	)`, a, viewer, blockedBetween(viewer, a+".user_id"))
+}
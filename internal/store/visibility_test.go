@@ -0,0 +1,108 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"testing"
+)
+
+var visibilities = []string{VisibilityPublic, VisibilityFollowers, VisibilityUnlisted, VisibilityPrivate}
+
+func TestPostVisibleTo(t *testing.T) {
+	const authorID, otherID = 1, 2
+
+	tests := []struct {
+		viewer     string
+		viewerID   int64
+		isFollower bool
+		want       map[string]bool
+	}{
+		{"author", authorID, false, map[string]bool{
+			VisibilityPublic: true, VisibilityFollowers: true, VisibilityUnlisted: true, VisibilityPrivate: true,
+		}},
+		{"follower", otherID, true, map[string]bool{
+			VisibilityPublic: true, VisibilityFollowers: true, VisibilityUnlisted: true, VisibilityPrivate: false,
+		}},
+		{"non-follower", otherID, false, map[string]bool{
+			VisibilityPublic: true, VisibilityFollowers: false, VisibilityUnlisted: true, VisibilityPrivate: false,
+		}},
+		{"anonymous", 0, false, map[string]bool{
+			VisibilityPublic: true, VisibilityFollowers: false, VisibilityUnlisted: true, VisibilityPrivate: false,
+		}},
+	}
+
+	for _, tt := range tests {
+		for _, visibility := range visibilities {
+			post := &Post{UserID: authorID, Visibility: visibility}
+			if got := post.VisibleTo(tt.viewerID, tt.isFollower); got != tt.want[visibility] {
+				t.Errorf("%s viewing %s post: VisibleTo = %v, want %v", tt.viewer, visibility, got, tt.want[visibility])
+			}
+		}
+	}
+}
+
+func TestVisiblePostsClause(t *testing.T) {
+	db := newTestDB(t)
+	ctx := context.Background()
+
+	author := createTestUser(t, db, "author")
+	follower := createTestUser(t, db, "follower")
+	stranger := createTestUser(t, db, "stranger")
+	blocked := createTestUser(t, db, "blocked")
+
+	if err := (&FollowerStore{db}).Follow(ctx, follower.ID, author.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := (&BlockStore{db}).Block(ctx, author.ID, blocked.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	posts := map[int64]string{}
+	for _, visibility := range visibilities {
+		posts[createTestPost(t, db, author.ID, visibility).ID] = visibility
+	}
+
+	// Unlisted posts are reachable by link but never listed to others.
+	tests := []struct {
+		viewer   string
+		viewerID int64
+		want     map[string]bool
+	}{
+		{"author", author.ID, map[string]bool{
+			VisibilityPublic: true, VisibilityFollowers: true, VisibilityUnlisted: true, VisibilityPrivate: true,
+		}},
+		{"follower", follower.ID, map[string]bool{VisibilityPublic: true, VisibilityFollowers: true}},
+		{"non-follower", stranger.ID, map[string]bool{VisibilityPublic: true}},
+		{"anonymous", 0, map[string]bool{VisibilityPublic: true}},
+		{"blocked", blocked.ID, map[string]bool{}},
+	}
+
+	query := fmt.Sprintf(`SELECT p.id FROM posts p WHERE p.user_id = $2 AND %s`, visiblePostsClause(1))
+	for _, tt := range tests {
+		t.Run(tt.viewer, func(t *testing.T) {
+			rows, err := db.QueryContext(ctx, query, tt.viewerID, author.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer rows.Close()
+
+			got := map[string]bool{}
+			for rows.Next() {
+				var id int64
+				if err := rows.Scan(&id); err != nil {
+					t.Fatal(err)
+				}
+				got[posts[id]] = true
+			}
+			if err := rows.Err(); err != nil {
+				t.Fatal(err)
+			}
+
+			for _, visibility := range visibilities {
+				if got[visibility] != tt.want[visibility] {
+					t.Errorf("%s post listed = %v, want %v", visibility, got[visibility], tt.want[visibility])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,255 @@
+// Package unfurl fetches web pages and extracts the OpenGraph and Twitter
+// card metadata used to render link previews.
+//
+// Pages are fetched on behalf of users, so the client refuses to connect to
+// loopback, private, link-local and other non-public addresses. The check
+// runs on the resolved address of every connection, including redirects,
+// which also defeats DNS rebinding.
+package unfurl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+
+	"golang.org/x/net/html"
+)
+
+const (
+	maxRedirects      = 3
+	maxTitleLen       = 300
+	maxDescriptionLen = 1000
+)
+
+var (
+	ErrBlockedAddress = errors.New("unfurl: address is not publicly routable")
+	ErrNotHTML        = errors.New("unfurl: response is not an HTML page")
+	ErrUnsupportedURL = errors.New("unfurl: only http and https URLs are supported")
+)
+
+type Preview struct {
+	URL         string
+	Title       string
+	Description string
+	ImageURL    string
+	SiteName    string
+}
+
+type Client struct {
+	http     *http.Client
+	maxBytes int64
+
+	// checkAddr vets the address of every connection. Tests swap it to
+	// reach servers on loopback.
+	checkAddr func(netip.Addr) error
+}
+
+// New returns a Client whose requests time out after timeout and which reads
+// at most maxBytes of each page.
+func New(timeout time.Duration, maxBytes int64) *Client {
+	c := &Client{maxBytes: maxBytes, checkAddr: checkPublic}
+
+	dialer := &net.Dialer{
+		Timeout: timeout,
+		Control: c.control,
+	}
+
+	transport := &http.Transport{
+		// A proxy would make the dialer check the proxy's address instead of
+		// the target's, so never use one.
+		Proxy:                 nil,
+		DialContext:           dialer.DialContext,
+		TLSHandshakeTimeout:   timeout,
+		ResponseHeaderTimeout: timeout,
+		MaxIdleConns:          10,
+		IdleConnTimeout:       30 * time.Second,
+	}
+
+	c.http = &http.Client{
+		Transport: transport,
+		Timeout:   timeout,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= maxRedirects {
+				return fmt.Errorf("unfurl: stopped after %d redirects", maxRedirects)
+			}
+			if req.URL.Scheme != "http" && req.URL.Scheme != "https" {
+				return ErrUnsupportedURL
+			}
+			return nil
+		},
+	}
+	return c
+}
+
+// Fetch downloads the page at rawURL and extracts its preview metadata.
+func (c *Client) Fetch(ctx context.Context, rawURL string) (*Preview, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, ErrUnsupportedURL
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "text/html")
+	req.Header.Set("User-Agent", "GopherSocialBot/1.0 (+link preview)")
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unfurl: unexpected status %d", resp.StatusCode)
+	}
+
+	mediaType, _, err := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if err != nil || (mediaType != "text/html" && mediaType != "application/xhtml+xml") {
+		return nil, ErrNotHTML
+	}
+
+	preview := parse(io.LimitReader(resp.Body, c.maxBytes), resp.Request.URL)
+	preview.URL = rawURL
+	return preview, nil
+}
+
+// control is the net.Dialer Control hook running checkAddr on the resolved
+// address of each connection.
+func (c *Client) control(network, address string, _ syscall.RawConn) error {
+	addrPort, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return ErrBlockedAddress
+	}
+	return c.checkAddr(addrPort.Addr().Unmap())
+}
+
+// checkPublic rejects addresses that are not publicly routable.
+func checkPublic(addr netip.Addr) error {
+	if !addr.IsGlobalUnicast() || addr.IsPrivate() || isReserved(addr) {
+		return ErrBlockedAddress
+	}
+	return nil
+}
+
+var reservedPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"),
+	netip.MustParsePrefix("192.0.0.0/24"),
+	netip.MustParsePrefix("192.0.2.0/24"),
+	netip.MustParsePrefix("198.18.0.0/15"),
+	netip.MustParsePrefix("198.51.100.0/24"),
+	netip.MustParsePrefix("203.0.113.0/24"),
+	netip.MustParsePrefix("240.0.0.0/4"),
+	netip.MustParsePrefix("64:ff9b::/96"),
+	netip.MustParsePrefix("2001:db8::/32"),
+}
+
+// isReserved reports addresses that IsGlobalUnicast accepts but that are
+// shared, documentation or otherwise reserved ranges.
+func isReserved(addr netip.Addr) bool {
+	for _, prefix := range reservedPrefixes {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// parse reads the head of an HTML document and collects its OpenGraph and
+// Twitter card tags, falling back to <title> and the description meta tag.
+func parse(r io.Reader, base *url.URL) *Preview {
+	meta := map[string]string{}
+	var title string
+
+	z := html.NewTokenizer(r)
+	for {
+		tt := z.Next()
+		switch tt {
+		case html.ErrorToken:
+			return buildPreview(meta, title, base)
+		case html.EndTagToken:
+			if name, _ := z.TagName(); string(name) == "head" {
+				return buildPreview(meta, title, base)
+			}
+		case html.StartTagToken, html.SelfClosingTagToken:
+			name, hasAttr := z.TagName()
+			switch string(name) {
+			case "body":
+				return buildPreview(meta, title, base)
+			case "title":
+				if tt == html.StartTagToken && z.Next() == html.TextToken {
+					title = string(z.Text())
+				}
+			case "meta":
+				var key, content string
+				for hasAttr {
+					var k, v []byte
+					k, v, hasAttr = z.TagAttr()
+					switch string(k) {
+					case "property", "name":
+						key = strings.ToLower(string(v))
+					case "content":
+						content = string(v)
+					}
+				}
+				if _, seen := meta[key]; key != "" && !seen {
+					meta[key] = content
+				}
+			}
+		}
+	}
+}
+
+func buildPreview(meta map[string]string, title string, base *url.URL) *Preview {
+	first := func(keys ...string) string {
+		for _, k := range keys {
+			if v := strings.TrimSpace(meta[k]); v != "" {
+				return v
+			}
+		}
+		return ""
+	}
+
+	p := &Preview{
+		Title:       first("og:title", "twitter:title"),
+		Description: first("og:description", "twitter:description", "description"),
+		SiteName:    first("og:site_name"),
+	}
+	if p.Title == "" {
+		p.Title = strings.TrimSpace(title)
+	}
+	p.Title = truncate(p.Title, maxTitleLen)
+	p.Description = truncate(p.Description, maxDescriptionLen)
+	p.SiteName = truncate(p.SiteName, maxTitleLen)
+
+	if image := first("og:image", "og:image:url", "twitter:image", "twitter:image:src"); image != "" {
+		if ref, err := url.Parse(image); err == nil {
+			if abs := base.ResolveReference(ref); abs.Scheme == "http" || abs.Scheme == "https" {
+				p.ImageURL = abs.String()
+			}
+		}
+	}
+	return p
+}
+
+// truncate shortens s to at most n runes.
+func truncate(s string, n int) string {
+	if r := []rune(s); len(r) > n {
+		return string(r[:n])
+	}
+	return s
+}
@@ -0,0 +1,235 @@
+package unfurl
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newTestClient returns a client allowed to reach the loopback servers of
+// httptest.
+func newTestClient(timeout time.Duration, maxBytes int64) *Client {
+	c := New(timeout, maxBytes)
+	c.checkAddr = func(netip.Addr) error { return nil }
+	return c
+}
+
+func serve(t *testing.T, contentType, body string) *httptest.Server {
+	t.Helper()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", contentType)
+		w.Write([]byte(body))
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestFetchExtractsMetadata(t *testing.T) {
+	tests := []struct {
+		name string
+		page string
+		want Preview
+	}{
+		{
+			name: "opengraph",
+			page: `<html><head>
+				<title>Fallback title</title>
+				<meta property="og:title" content="OG title">
+				<meta property="og:description" content="OG description">
+				<meta property="og:site_name" content="Example">
+				<meta property="og:image" content="/img/cover.png">
+			</head><body></body></html>`,
+			want: Preview{
+				Title:       "OG title",
+				Description: "OG description",
+				SiteName:    "Example",
+				ImageURL:    "/img/cover.png",
+			},
+		},
+		{
+			name: "twitter card",
+			page: `<head>
+				<meta name="twitter:title" content="Card title">
+				<meta name="twitter:description" content="Card description">
+				<meta name="twitter:image" content="https://cdn.example.com/card.jpg">
+			</head>`,
+			want: Preview{
+				Title:       "Card title",
+				Description: "Card description",
+				ImageURL:    "https://cdn.example.com/card.jpg",
+			},
+		},
+		{
+			name: "title and description fallback",
+			page: `<head><title> Plain page </title><meta name="description" content="About it"></head>`,
+			want: Preview{Title: "Plain page", Description: "About it"},
+		},
+		{
+			name: "tags after head are ignored",
+			page: `<head><title>Head</title></head><body><meta property="og:title" content="Body"></body>`,
+			want: Preview{Title: "Head"},
+		},
+		{
+			name: "non-http image is dropped",
+			page: `<head><meta property="og:title" content="T"><meta property="og:image" content="javascript:alert(1)"></head>`,
+			want: Preview{Title: "T"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := serve(t, "text/html; charset=utf-8", tt.page)
+
+			got, err := newTestClient(time.Second, 1<<20).Fetch(context.Background(), srv.URL+"/page")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			want := tt.want
+			want.URL = srv.URL + "/page"
+			if strings.HasPrefix(want.ImageURL, "/") {
+				want.ImageURL = srv.URL + want.ImageURL
+			}
+			if *got != want {
+				t.Errorf("Fetch() = %+v, want %+v", *got, want)
+			}
+		})
+	}
+}
+
+func TestFetchReadsAtMostMaxBytes(t *testing.T) {
+	page := "<head><title>Early</title>" + strings.Repeat("<!-- padding -->", 100) +
+		`<meta property="og:description" content="Too late"></head>`
+	srv := serve(t, "text/html", page)
+
+	got, err := newTestClient(time.Second, 64).Fetch(context.Background(), srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Title != "Early" || got.Description != "" {
+		t.Errorf("Fetch() = %+v, want only the title within the first 64 bytes", *got)
+	}
+}
+
+func TestFetchTimesOut(t *testing.T) {
+	done := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-done:
+		case <-r.Context().Done():
+		}
+	}))
+	t.Cleanup(srv.Close)
+	t.Cleanup(func() { close(done) })
+
+	start := time.Now()
+	_, err := newTestClient(100*time.Millisecond, 1<<20).Fetch(context.Background(), srv.URL)
+	if err == nil {
+		t.Fatal("Fetch() succeeded against a server that never answers")
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("Fetch() took %s, want it to give up after the timeout", elapsed)
+	}
+}
+
+func TestFetchRejectsNonHTML(t *testing.T) {
+	for _, contentType := range []string{"application/json", "image/png", ""} {
+		srv := serve(t, contentType, `{"title": "not a page"}`)
+
+		_, err := newTestClient(time.Second, 1<<20).Fetch(context.Background(), srv.URL)
+		if !errors.Is(err, ErrNotHTML) {
+			t.Errorf("Fetch() of %q: got %v, want ErrNotHTML", contentType, err)
+		}
+	}
+}
+
+func TestFetchRejectsUnsupportedScheme(t *testing.T) {
+	_, err := New(time.Second, 1<<20).Fetch(context.Background(), "file:///etc/passwd")
+	if !errors.Is(err, ErrUnsupportedURL) {
+		t.Errorf("got %v, want ErrUnsupportedURL", err)
+	}
+}
+
+func TestFetchRejectsPrivateAddress(t *testing.T) {
+	srv := serve(t, "text/html", "<title>Internal</title>")
+
+	_, err := New(time.Second, 1<<20).Fetch(context.Background(), srv.URL)
+	if !errors.Is(err, ErrBlockedAddress) {
+		t.Errorf("Fetch() of a loopback server: got %v, want ErrBlockedAddress", err)
+	}
+}
+
+func TestCheckPublic(t *testing.T) {
+	tests := []struct {
+		addr    string
+		blocked bool
+	}{
+		{"93.184.216.34", false},
+		{"2606:2800:220:1:248:1893:25c8:1946", false},
+		{"127.0.0.1", true},
+		{"::1", true},
+		{"10.1.2.3", true},
+		{"172.16.0.1", true},
+		{"192.168.1.1", true},
+		{"169.254.169.254", true},
+		{"fe80::1", true},
+		{"fd00::1", true},
+		{"100.64.0.1", true},
+		{"0.0.0.0", true},
+		{"224.0.0.1", true},
+		{"198.51.100.7", true},
+	}
+
+	for _, tt := range tests {
+		err := checkPublic(netip.MustParseAddr(tt.addr))
+		if blocked := errors.Is(err, ErrBlockedAddress); blocked != tt.blocked {
+			t.Errorf("checkPublic(%s) blocked = %v, want %v", tt.addr, blocked, tt.blocked)
+		}
+	}
+}
+
+func TestFetchRejectsRedirectToPrivateAddress(t *testing.T) {
+	targets := []string{
+		"http://127.0.0.2/",
+		"http://10.0.0.1/",
+		"http://169.254.169.254/latest/meta-data/",
+		"http://[::1]/",
+	}
+
+	for _, target := range targets {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == "/page" {
+				w.Header().Set("Content-Type", "text/html")
+				w.Write([]byte("<title>Allowed</title>"))
+				return
+			}
+			http.Redirect(w, r, target, http.StatusFound)
+		}))
+		t.Cleanup(srv.Close)
+
+		// Only the test server itself is reachable; every other address
+		// goes through the real check.
+		c := New(time.Second, 1<<20)
+		c.checkAddr = func(addr netip.Addr) error {
+			if addr == netip.MustParseAddr("127.0.0.1") {
+				return nil
+			}
+			return checkPublic(addr)
+		}
+
+		if _, err := c.Fetch(context.Background(), srv.URL+"/page"); err != nil {
+			t.Fatalf("Fetch() of the allowed server: %v", err)
+		}
+
+		_, err := c.Fetch(context.Background(), srv.URL+"/redirect")
+		if !errors.Is(err, ErrBlockedAddress) {
+			t.Errorf("Fetch() redirected to %s: got %v, want ErrBlockedAddress", target, err)
+		}
+	}
+}